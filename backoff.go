@@ -0,0 +1,41 @@
+package transparencyprocessor
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// backoff computes randomized exponential retry intervals for failed fetches.
+type backoff struct {
+	initial    time.Duration
+	max        time.Duration
+	multiplier float64
+	jitter     float64
+	rand       func() float64
+}
+
+func newBackoff(cfg BackoffConfig) *backoff {
+	return &backoff{
+		initial:    cfg.InitialInterval,
+		max:        cfg.MaxInterval,
+		multiplier: cfg.Multiplier,
+		jitter:     cfg.RandomizationFactor,
+		rand:       rand.Float64,
+	}
+}
+
+// interval returns how long to wait before retrying after the given number of
+// consecutive failures. The result lies within ±jitter of the exponential
+// interval, which itself never exceeds max.
+func (b *backoff) interval(failures int) time.Duration {
+	if failures < 1 {
+		failures = 1
+	}
+	d := float64(b.initial) * math.Pow(b.multiplier, float64(failures-1))
+	if d > float64(b.max) {
+		d = float64(b.max)
+	}
+	delta := b.jitter * d
+	return time.Duration(d - delta + b.rand()*2*delta)
+}
@@ -0,0 +1,175 @@
+package transparencyprocessor
+
+import (
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// cacheEntry holds the attributes for one host/path together with what is
+// needed to fetch them again. An entry with failures > 0 is a negative entry:
+// the last fetch failed and the key is not retried before retryAt.
+type cacheEntry struct {
+	// lastAccess is the time of the last get in Unix nanoseconds. It is
+	// updated atomically under the read lock and kept first for alignment.
+	lastAccess int64
+	host       string
+	path       string
+	attributes tiltAttributes
+	failures   int
+	retryAt    time.Time
+}
+
+// stale reports whether the entry should be fetched again at time now.
+func (e *cacheEntry) stale(now time.Time, ttl time.Duration) bool {
+	if e.failures > 0 {
+		return !now.Before(e.retryAt)
+	}
+	return now.Sub(e.attributes.lastUpdated) >= ttl
+}
+
+// idle reports whether the entry was not accessed for longer than ttl.
+func (e *cacheEntry) idle(now time.Time, ttl time.Duration) bool {
+	return now.Sub(time.Unix(0, atomic.LoadInt64(&e.lastAccess))) > ttl
+}
+
+// attributesCache stores tiltAttributes by attributeKey. Entries older than
+// ttl are stale: they are still returned, but callers are expected to refresh
+// them. Failed fetches are recorded with fail and become stale once their
+// backoff interval passed. Entries that are not accessed for longer than ttl
+// are evicted by expiring.
+type attributesCache struct {
+	mu      sync.RWMutex
+	entries map[string]*cacheEntry
+	ttl     time.Duration
+	backoff *backoff
+	now     func() time.Time
+}
+
+func newAttributesCache(ttl time.Duration, backoff *backoff) *attributesCache {
+	return &attributesCache{
+		entries: make(map[string]*cacheEntry),
+		ttl:     ttl,
+		backoff: backoff,
+		now:     time.Now,
+	}
+}
+
+// get returns the attributes cached for key, whether an entry was found and
+// whether that entry is stale.
+func (c *attributesCache) get(key string) (tiltAttributes, bool, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return tiltAttributes{}, false, false
+	}
+	now := c.now()
+	atomic.StoreInt64(&e.lastAccess, now.UnixNano())
+	return e.attributes, true, e.stale(now, c.ttl)
+}
+
+// set stores attributes for key and stamps them with the current time. A
+// refreshed entry keeps its last access.
+func (c *attributesCache) set(key, host, path string, attributes tiltAttributes) {
+	now := c.now()
+	attributes.lastUpdated = now
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = &cacheEntry{lastAccess: c.lastAccess(key, now), host: host, path: path, attributes: attributes}
+}
+
+// restore stores attributes for key keeping their lastUpdated, e.g. when
+// loading a snapshot.
+func (c *attributesCache) restore(key, host, path string, attributes tiltAttributes) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = &cacheEntry{lastAccess: c.lastAccess(key, c.now()), host: host, path: path, attributes: attributes}
+}
+
+// lastAccess returns the last access of the entry for key, or now if there is
+// none. The caller must hold c.mu.
+func (c *attributesCache) lastAccess(key string, now time.Time) int64 {
+	if e, ok := c.entries[key]; ok {
+		return atomic.LoadInt64(&e.lastAccess)
+	}
+	return now.UnixNano()
+}
+
+// len returns the number of entries.
+func (c *attributesCache) len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.entries)
+}
+
+// remove deletes the entry for key.
+func (c *attributesCache) remove(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, key)
+}
+
+// fail records a failed fetch for key. A previously cached entry keeps its
+// attributes, otherwise an empty negative entry is stored. Either way the key
+// is not considered stale again before the backoff interval for its number of
+// consecutive failures has passed.
+func (c *attributesCache) fail(key, host, path string) {
+	now := c.now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		e = &cacheEntry{lastAccess: now.UnixNano(), host: host, path: path, attributes: tiltAttributes{lastUpdated: now}}
+		c.entries[key] = e
+	}
+	e.failures++
+	e.retryAt = now.Add(c.backoff.interval(e.failures))
+}
+
+// expiring returns the keys of all entries that expire within the given
+// window. Entries that were not accessed for longer than ttl are removed
+// instead of being returned. Negative entries are never returned, they are
+// retried once a span asks for them after their retry time.
+func (c *attributesCache) expiring(within time.Duration) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	var keys []string
+	for k, e := range c.entries {
+		if e.idle(now, c.ttl) {
+			delete(c.entries, k)
+			continue
+		}
+		if e.failures == 0 && e.stale(now.Add(within), c.ttl) {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// source returns the host and path the entry for key was fetched from.
+func (c *attributesCache) source(key string) (string, string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return "", "", false
+	}
+	return e.host, e.path, true
+}
+
+// keys returns the keys of all entries fetched for host, with or without port.
+func (c *attributesCache) keys(host string) []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var keys []string
+	for k, e := range c.entries {
+		h, _, err := net.SplitHostPort(e.host)
+		if e.host == host || (err == nil && h == host) {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
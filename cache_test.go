@@ -0,0 +1,136 @@
+package transparencyprocessor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	return c.t
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.t = c.t.Add(d)
+}
+
+func newTestCache(ttl time.Duration) (*attributesCache, *fakeClock) {
+	clock := &fakeClock{t: time.Unix(1600000000, 0)}
+	b := newBackoff(BackoffConfig{InitialInterval: 10 * time.Second, MaxInterval: time.Minute, Multiplier: 2})
+	c := newAttributesCache(ttl, b)
+	c.now = clock.now
+	return c, clock
+}
+
+func TestAttributesCacheExpiry(t *testing.T) {
+	c, clock := newTestCache(time.Minute)
+
+	_, ok, _ := c.get("host/path")
+	assert.False(t, ok)
+
+	c.set("host/path", "host", "path", tiltAttributes{categories: []string{"a"}})
+	attr, ok, stale := c.get("host/path")
+	assert.True(t, ok)
+	assert.False(t, stale)
+	assert.Equal(t, []string{"a"}, attr.categories)
+
+	clock.advance(time.Minute)
+	attr, ok, stale = c.get("host/path")
+	assert.True(t, ok)
+	assert.True(t, stale)
+	assert.Equal(t, []string{"a"}, attr.categories, "stale attributes are still served")
+}
+
+func TestAttributesCacheRefresh(t *testing.T) {
+	c, clock := newTestCache(time.Minute)
+	c.set("a", "host", "a", tiltAttributes{})
+	clock.advance(30 * time.Second)
+	c.set("b", "host", "b", tiltAttributes{})
+
+	assert.Empty(t, c.expiring(10*time.Second))
+	assert.Equal(t, []string{"a"}, c.expiring(30*time.Second))
+
+	c.set("a", "host", "a", tiltAttributes{})
+	assert.Empty(t, c.expiring(30*time.Second))
+
+	host, path, ok := c.source("a")
+	assert.True(t, ok)
+	assert.Equal(t, "host", host)
+	assert.Equal(t, "a", path)
+}
+
+func TestAttributesCacheEviction(t *testing.T) {
+	c, clock := newTestCache(time.Minute)
+	c.set("idle", "host", "/users/123", tiltAttributes{})
+	c.set("hot", "host", "/users/456", tiltAttributes{})
+
+	clock.advance(50 * time.Second)
+	c.get("hot")
+	assert.ElementsMatch(t, []string{"idle", "hot"}, c.expiring(10*time.Second))
+
+	// A refresh does not count as an access.
+	c.set("idle", "host", "/users/123", tiltAttributes{})
+	c.set("hot", "host", "/users/456", tiltAttributes{})
+	clock.advance(20 * time.Second)
+	assert.Empty(t, c.expiring(10*time.Second))
+	assert.Equal(t, 1, c.len(), "idle entries are evicted")
+	_, ok, _ := c.get("hot")
+	assert.True(t, ok)
+	_, ok, _ = c.get("idle")
+	assert.False(t, ok)
+}
+
+func TestAttributesCacheNegativeEntries(t *testing.T) {
+	c, clock := newTestCache(time.Minute)
+
+	c.fail("a", "host", "a")
+	_, ok, stale := c.get("a")
+	assert.True(t, ok)
+	assert.False(t, stale, "negative entry is served until retry")
+	assert.Empty(t, c.expiring(30*time.Second), "refresh ahead does not apply to negative entries")
+
+	clock.advance(10 * time.Second)
+	_, _, stale = c.get("a")
+	assert.True(t, stale)
+	assert.Empty(t, c.expiring(0), "negative entries are only retried on demand")
+
+	c.fail("a", "host", "a")
+	clock.advance(10 * time.Second)
+	_, _, stale = c.get("a")
+	assert.False(t, stale, "second failure doubles the interval")
+	clock.advance(10 * time.Second)
+	_, _, stale = c.get("a")
+	assert.True(t, stale)
+
+	c.set("a", "host", "a", tiltAttributes{categories: []string{"a"}})
+	c.fail("a", "host", "a")
+	attr, _, _ := c.get("a")
+	assert.Equal(t, []string{"a"}, attr.categories, "failed refresh keeps last known attributes")
+
+	c.fail("dead", "dead", "/")
+	clock.advance(time.Minute)
+	assert.Empty(t, c.expiring(0))
+	clock.advance(time.Second)
+	assert.Empty(t, c.expiring(0))
+	_, ok, _ = c.get("dead")
+	assert.False(t, ok, "idle negative entries are evicted")
+}
+
+func TestBackoffInterval(t *testing.T) {
+	b := newBackoff(BackoffConfig{InitialInterval: time.Second, MaxInterval: 10 * time.Second, Multiplier: 2, RandomizationFactor: 0.5})
+
+	b.rand = func() float64 { return 0.5 }
+	assert.Equal(t, time.Second, b.interval(1))
+	assert.Equal(t, 4*time.Second, b.interval(3))
+	assert.Equal(t, 10*time.Second, b.interval(10))
+
+	b.rand = func() float64 { return 0 }
+	assert.Equal(t, 500*time.Millisecond, b.interval(1))
+	b.rand = func() float64 { return 1 }
+	assert.Equal(t, 15*time.Second, b.interval(10))
+}
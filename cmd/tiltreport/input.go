@@ -0,0 +1,271 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"gopkg.in/yaml.v3"
+
+	"github.com/mindtastic/opentelemetry-transparency-processor/internal/extractor"
+	"github.com/mindtastic/opentelemetry-transparency-processor/tilt"
+)
+
+// documentSet holds the TILT document of every host.
+type documentSet map[string]*tilt.Document
+
+// lookup returns the document of host, with or without port.
+func (s documentSet) lookup(host string) (*tilt.Document, bool) {
+	if doc, ok := s[host]; ok {
+		return doc, true
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		doc, ok := s[h]
+		return doc, ok
+	}
+	return nil, false
+}
+
+// observedService is a destination seen in the traces, with the tilt.*
+// attributes of its spans for services without a document.
+type observedService struct {
+	categories       map[string]struct{}
+	purposes         map[string]struct{}
+	legalBases       map[string]struct{}
+	storageDurations map[string]struct{}
+}
+
+// readCache reads the documents of a cache snapshot file or URL into docs.
+func readCache(source string, docs documentSet) error {
+	var b []byte
+	var err error
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		b, err = fetch(source)
+	} else {
+		b, err = os.ReadFile(source)
+	}
+	if err != nil {
+		return fmt.Errorf("error reading cache %s: %w", source, err)
+	}
+
+	var snapshot struct {
+		Entries []struct {
+			Host     string          `json:"host"`
+			Document json.RawMessage `json:"document"`
+		} `json:"entries"`
+	}
+	if err = json.Unmarshal(b, &snapshot); err != nil {
+		return fmt.Errorf("error decoding cache %s: %w", source, err)
+	}
+	for _, e := range snapshot.Entries {
+		doc, err := tilt.Unmarshal(e.Document)
+		if err != nil {
+			return fmt.Errorf("error decoding cached document of %s: %w", e.Host, err)
+		}
+		// Paths of a host usually share one document, the first one wins.
+		if _, ok := docs[e.Host]; !ok {
+			docs[e.Host] = doc
+		}
+	}
+	return nil
+}
+
+func fetch(url string) ([]byte, error) {
+	client := http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// readDocuments reads <host>.json documents of dir into docs. They take
+// precedence over cached documents.
+func readDocuments(dir string, docs documentSet) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			return err
+		}
+		doc, err := tilt.Unmarshal(b)
+		if err != nil {
+			return fmt.Errorf("%s: %w", f, err)
+		}
+		docs[strings.TrimSuffix(filepath.Base(f), ".json")] = doc
+	}
+	return nil
+}
+
+// readExtractors returns the extractors of the processor with the given id in
+// the collector configuration file, or the default ones if it sets none.
+func readExtractors(file, id string) ([]string, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var cfg struct {
+		Processors map[string]*struct {
+			Extractors []string `yaml:"extractors"`
+		} `yaml:"processors"`
+	}
+	if err = yaml.Unmarshal(b, &cfg); err != nil {
+		return nil, fmt.Errorf("error decoding %s: %w", file, err)
+	}
+	processor, ok := cfg.Processors[id]
+	if !ok {
+		return nil, fmt.Errorf("%s: no processor %q", file, id)
+	}
+	if processor == nil || len(processor.Extractors) == 0 {
+		return extractor.Default, nil
+	}
+	for _, e := range processor.Extractors {
+		if !extractor.Known(e) {
+			return nil, fmt.Errorf("%s: unknown extractor %q, valid extractors are: %v", file, e, extractor.Default)
+		}
+	}
+	return processor.Extractors, nil
+}
+
+// readTraces returns the services that enriched spans of an OTLP JSON export
+// were sent to, keyed by the host without port the extractors derive for them.
+func readTraces(file string, extractors []string) (map[string]*observedService, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	observed := make(map[string]*observedService)
+	unmarshaler := ptrace.NewJSONUnmarshaler()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		b := bytes.TrimSpace(scanner.Bytes())
+		if len(b) == 0 {
+			continue
+		}
+		td, err := unmarshaler.UnmarshalTraces(b)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", file, line, err)
+		}
+		collectServices(td, extractors, observed)
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", file, err)
+	}
+	return observed, nil
+}
+
+func collectServices(td ptrace.Traces, extractors []string, observed map[string]*observedService) {
+	rss := td.ResourceSpans()
+	for i := 0; i < rss.Len(); i++ {
+		resource := rss.At(i).Resource().Attributes()
+		ilss := rss.At(i).ScopeSpans()
+		for j := 0; j < ilss.Len(); j++ {
+			spans := ilss.At(j).Spans()
+			for k := 0; k < spans.Len(); k++ {
+				span := spans.At(k)
+				if !enriched(span) {
+					continue
+				}
+				host, _, ok := extractor.HostPath(extractors, extractor.Span{
+					Name:     span.Name(),
+					Attrs:    span.Attributes(),
+					Resource: resource,
+				})
+				if !ok {
+					continue
+				}
+				// A service is reported once, whether it was called with or without port.
+				if h, _, err := net.SplitHostPort(host); err == nil {
+					host = h
+				}
+				s, ok := observed[host]
+				if !ok {
+					s = &observedService{
+						categories:       make(map[string]struct{}),
+						purposes:         make(map[string]struct{}),
+						legalBases:       make(map[string]struct{}),
+						storageDurations: make(map[string]struct{}),
+					}
+					observed[host] = s
+				}
+				s.addAttributes(span.Attributes())
+				events := span.Events()
+				for e := 0; e < events.Len(); e++ {
+					if events.At(e).Name() == attrDataDisclosed {
+						s.addAttributes(events.At(e).Attributes())
+					}
+				}
+			}
+		}
+	}
+}
+
+// attrDataDisclosed names the span events of the events output mode and
+// prefixes the attributes of the indexed output mode.
+const attrDataDisclosed = "tilt.data_disclosed"
+
+// enriched reports whether the transparency processor added attributes or
+// events to span.
+func enriched(span ptrace.Span) bool {
+	found := false
+	span.Attributes().Range(func(k string, _ pcommon.Value) bool {
+		found = strings.HasPrefix(k, "tilt.")
+		return !found
+	})
+	events := span.Events()
+	for i := 0; i < events.Len() && !found; i++ {
+		found = events.At(i).Name() == attrDataDisclosed
+	}
+	return found
+}
+
+// addAttributes adds the values of the tilt.* attributes of every output mode
+// to s, e.g. tilt.categories, tilt.data_disclosed.0.category or the
+// tilt.category of an event.
+func (s *observedService) addAttributes(attrs pcommon.Map) {
+	sets := map[string]map[string]struct{}{
+		"categories":        s.categories,
+		"category":          s.categories,
+		"purposes":          s.purposes,
+		"legal_bases":       s.legalBases,
+		"storage_durations": s.storageDurations,
+	}
+	attrs.Range(func(k string, v pcommon.Value) bool {
+		if !strings.HasPrefix(k, "tilt.") {
+			return true
+		}
+		set, ok := sets[k[strings.LastIndex(k, ".")+1:]]
+		if !ok {
+			return true
+		}
+		switch v.Type() {
+		case pcommon.ValueTypeString:
+			set[v.StringVal()] = struct{}{}
+		case pcommon.ValueTypeSlice:
+			for i := 0; i < v.SliceVal().Len(); i++ {
+				set[v.SliceVal().At(i).AsString()] = struct{}{}
+			}
+		}
+		return true
+	})
+}
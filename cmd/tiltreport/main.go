@@ -0,0 +1,105 @@
+// Command tiltreport writes the records of processing activities required by
+// Art. 30 GDPR from what the transparency processor observed.
+//
+// Services are taken from OTLP JSON trace exports, e.g. of the file exporter,
+// and described by their TILT documents, read from the processor's cache (a
+// snapshot file or the /cache endpoint of its server) or from a directory of
+// <host>.json files. Without traces, every known document is reported. The
+// services of spans are derived with the extractors of the processor in the
+// collector configuration given by -config, or the default ones.
+//
+//	tiltreport -config config.yaml -traces traces.json -cache http://localhost:13134/cache -markdown register.md -csv register.csv
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/mindtastic/opentelemetry-transparency-processor/internal/extractor"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("tiltreport", flag.ContinueOnError)
+	traces := fs.String("traces", "", "OTLP JSON trace export, one request per line")
+	config := fs.String("config", "", "collector configuration to read the extractors of the processor from")
+	processor := fs.String("processor", "transparency", "id of the processor in -config")
+	cache := fs.String("cache", "", "cache snapshot file or URL of the processor's /cache endpoint")
+	documents := fs.String("documents", "", "directory of TILT documents named <host>.json")
+	csvOut := fs.String("csv", "", "write the register as CSV to this file, - for stdout")
+	markdownOut := fs.String("markdown", "", "write the register as Markdown to this file, - for stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *cache == "" && *documents == "" {
+		return errors.New("at least one of -cache or -documents must be specified")
+	}
+	if *csvOut == "" && *markdownOut == "" {
+		return errors.New("at least one of -csv or -markdown must be specified")
+	}
+
+	docs := make(documentSet)
+	if *cache != "" {
+		if err := readCache(*cache, docs); err != nil {
+			return err
+		}
+	}
+	if *documents != "" {
+		if err := readDocuments(*documents, docs); err != nil {
+			return err
+		}
+	}
+
+	extractors := extractor.Default
+	if *config != "" {
+		var err error
+		if extractors, err = readExtractors(*config, *processor); err != nil {
+			return err
+		}
+	}
+
+	var observed map[string]*observedService
+	if *traces != "" {
+		var err error
+		if observed, err = readTraces(*traces, extractors); err != nil {
+			return err
+		}
+	}
+
+	records := buildRegister(docs, observed)
+	if *csvOut != "" {
+		if err := writeOutput(*csvOut, stdout, func(w io.Writer) error { return writeCSV(w, records) }); err != nil {
+			return err
+		}
+	}
+	if *markdownOut != "" {
+		if err := writeOutput(*markdownOut, stdout, func(w io.Writer) error { return writeMarkdown(w, records) }); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeOutput(file string, stdout io.Writer, write func(io.Writer) error) error {
+	if file == "-" {
+		return write(stdout)
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if err = write(f); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("error writing %s: %w", file, err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+
+	"github.com/mindtastic/opentelemetry-transparency-processor/internal/extractor"
+)
+
+// writeTraces writes an OTLP JSON export with a span to each host.
+func writeTraces(t *testing.T, spans ...map[string]interface{}) string {
+	td := ptrace.NewTraces()
+	ss := td.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans()
+	for _, attrs := range spans {
+		pcommon.NewMapFromRaw(attrs).CopyTo(ss.AppendEmpty().Attributes())
+	}
+	b, err := ptrace.NewJSONMarshaler().MarshalTraces(td)
+	require.NoError(t, err)
+	file := filepath.Join(t.TempDir(), "traces.json")
+	require.NoError(t, os.WriteFile(file, append(b, '\n'), 0600))
+	return file
+}
+
+func TestRunMarkdown(t *testing.T) {
+	traces := writeTraces(t,
+		map[string]interface{}{"http.host": "users:8080", "tilt.categories": []interface{}{"email"}},
+		map[string]interface{}{"http.host": "legacy", "tilt.categories": []interface{}{"health", "email"}, "tilt.purposes": []interface{}{"therapy"}},
+		map[string]interface{}{"http.host": "orders"},
+	)
+
+	var out bytes.Buffer
+	require.NoError(t, run([]string{"-traces", traces, "-documents", "testdata", "-markdown", "-"}, &out))
+	assert.Equal(t, `# Records of processing activities
+
+## (no TILT document)
+
+### therapy
+
+| Service | Category | Legal bases | Storage | Recipients | Third countries |
+| --- | --- | --- | --- | --- | --- |
+| legacy | email |  |  |  |  |
+| legacy | health |  |  |  |  |
+
+## Shop GmbH
+
+### newsletter
+
+| Service | Category | Legal bases | Storage | Recipients | Third countries |
+| --- | --- | --- | --- | --- | --- |
+| users | email | GDPR-6-1-a | P1Y | Mail Inc. | US |
+
+### orders
+
+| Service | Category | Legal bases | Storage | Recipients | Third countries |
+| --- | --- | --- | --- | --- | --- |
+| users | address | GDPR-6-1-b |  |  | US |
+| users | email | GDPR-6-1-a | P1Y | Mail Inc. | US |
+`, out.String())
+}
+
+func TestRunCSVFromCache(t *testing.T) {
+	doc, err := os.ReadFile(filepath.Join("testdata", "users.json"))
+	require.NoError(t, err)
+	dir := t.TempDir()
+	cache := filepath.Join(dir, "cache.json")
+	require.NoError(t, os.WriteFile(cache, []byte(`{"version": 1, "entries": [{"key": "users/a", "host": "users", "path": "/a", "document": `+string(doc)+`}]}`), 0600))
+
+	csvFile := filepath.Join(dir, "register.csv")
+	require.NoError(t, run([]string{"-cache", cache, "-csv", csvFile}, nil))
+	b, err := os.ReadFile(csvFile)
+	require.NoError(t, err)
+	assert.Equal(t, `controller,purpose,service,category,legal_bases,storage_durations,recipients,third_countries
+Shop GmbH,newsletter,users,email,GDPR-6-1-a,P1Y,Mail Inc.,US
+Shop GmbH,orders,users,address,GDPR-6-1-b,,,US
+Shop GmbH,orders,users,email,GDPR-6-1-a,P1Y,Mail Inc.,US
+`, string(b))
+}
+
+func TestRunErrors(t *testing.T) {
+	assert.Error(t, run([]string{"-markdown", "-"}, nil), "no documents")
+	assert.Error(t, run([]string{"-documents", "testdata"}, nil), "no output")
+	assert.Error(t, run([]string{"-documents", "testdata", "-csv", "-", "-traces", "missing.json"}, nil))
+}
+
+func TestCollectServices(t *testing.T) {
+	td := ptrace.NewTraces()
+	spans := td.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans()
+
+	// flat output, keyed through http.url
+	pcommon.NewMapFromRaw(map[string]interface{}{
+		"http.url":        "https://users:8443/api/users?page=2",
+		"tilt.categories": []interface{}{"email"},
+		"tilt.purposes":   []interface{}{"newsletter"},
+	}).CopyTo(spans.AppendEmpty().Attributes())
+
+	// indexed output, keyed through server.address
+	pcommon.NewMapFromRaw(map[string]interface{}{
+		"server.address":                              "orders",
+		"url.path":                                    "/orders",
+		"tilt.data_disclosed.0.category":              "address",
+		"tilt.data_disclosed.0.purposes":              []interface{}{"shipping"},
+		"tilt.data_disclosed.0.legal_bases":           []interface{}{"Art. 6(1)(b) GDPR"},
+		"tilt.data_disclosed.0.legal_bases_canonical": []interface{}{"GDPR-6-1-b"},
+		"tilt.data_disclosed.1.category":              "phone",
+	}).CopyTo(spans.AppendEmpty().Attributes())
+
+	// events output, keyed through rpc.*
+	span := spans.AppendEmpty()
+	pcommon.NewMapFromRaw(map[string]interface{}{"rpc.service": "shop.Therapy", "rpc.method": "Book"}).CopyTo(span.Attributes())
+	ev := span.Events().AppendEmpty()
+	ev.SetName("tilt.data_disclosed")
+	pcommon.NewMapFromRaw(map[string]interface{}{
+		"tilt.category":          "health",
+		"tilt.purposes":          []interface{}{"therapy"},
+		"tilt.storage_durations": []interface{}{"P10Y"},
+	}).CopyTo(ev.Attributes())
+
+	// not enriched
+	pcommon.NewMapFromRaw(map[string]interface{}{"server.address": "legacy", "url.path": "/"}).CopyTo(spans.AppendEmpty().Attributes())
+
+	// the same service without port
+	pcommon.NewMapFromRaw(map[string]interface{}{
+		"http.host":       "users",
+		"tilt.categories": []interface{}{"name"},
+	}).CopyTo(spans.AppendEmpty().Attributes())
+
+	observed := make(map[string]*observedService)
+	collectServices(td, extractor.Default, observed)
+	require.Len(t, observed, 3)
+
+	set := func(values ...string) map[string]struct{} {
+		s := make(map[string]struct{}, len(values))
+		for _, v := range values {
+			s[v] = struct{}{}
+		}
+		return s
+	}
+	assert.Equal(t, &observedService{
+		categories:       set("email", "name"),
+		purposes:         set("newsletter"),
+		legalBases:       set(),
+		storageDurations: set(),
+	}, observed["users"])
+	assert.Equal(t, &observedService{
+		categories:       set("address", "phone"),
+		purposes:         set("shipping"),
+		legalBases:       set("Art. 6(1)(b) GDPR"),
+		storageDurations: set(),
+	}, observed["orders"])
+	assert.Equal(t, &observedService{
+		categories:       set("health"),
+		purposes:         set("therapy"),
+		legalBases:       set(),
+		storageDurations: set("P10Y"),
+	}, observed["shop.Therapy"])
+}
+
+func TestRunConfigExtractors(t *testing.T) {
+	traces := writeTraces(t, map[string]interface{}{
+		"http.host":       "legacy",
+		"rpc.service":     "shop.Therapy",
+		"rpc.method":      "Book",
+		"tilt.categories": []interface{}{"health"},
+	})
+	dir := t.TempDir()
+	config := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(config, []byte(`
+processors:
+  transparency:
+    extractors: [rpc, http.host]
+  transparency/unknown:
+    extractors: [grpc]
+  transparency/default:
+`), 0600))
+
+	var out bytes.Buffer
+	require.NoError(t, run([]string{"-config", config, "-traces", traces, "-documents", "testdata", "-csv", "-"}, &out))
+	assert.Equal(t, `controller,purpose,service,category,legal_bases,storage_durations,recipients,third_countries
+(no TILT document),(no purpose declared),shop.Therapy,health,,,,
+`, out.String())
+
+	out.Reset()
+	require.NoError(t, run([]string{"-config", config, "-processor", "transparency/default", "-traces", traces, "-documents", "testdata", "-csv", "-"}, &out))
+	assert.Contains(t, out.String(), ",legacy,health,")
+
+	assert.Error(t, run([]string{"-config", config, "-processor", "transparency/unknown", "-traces", traces, "-documents", "testdata", "-csv", "-"}, nil))
+	assert.Error(t, run([]string{"-config", config, "-processor", "transparency/missing", "-traces", traces, "-documents", "testdata", "-csv", "-"}, nil))
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/mindtastic/opentelemetry-transparency-processor/tilt"
+)
+
+const (
+	undocumentedController = "(no TILT document)"
+	unknownController      = "(unknown controller)"
+	noPurpose              = "(no purpose declared)"
+	noCategory             = "(no category declared)"
+)
+
+// record is one entry of the Art. 30 register: a category of personal data
+// processed by a service for a purpose.
+type record struct {
+	Controller       string
+	Purpose          string
+	Service          string
+	Category         string
+	LegalBases       []string
+	StorageDurations []string
+	Recipients       []string
+	ThirdCountries   []string
+}
+
+// buildRegister returns the records of the observed services, or of all
+// documents if observed is nil, sorted by controller, purpose, service and
+// category. Observed services without a document are reported with the
+// attributes of their spans, a record per purpose and category.
+func buildRegister(docs documentSet, observed map[string]*observedService) []record {
+	var records []record
+	if observed == nil {
+		for host, doc := range docs {
+			records = append(records, documentRecords(host, doc)...)
+		}
+	}
+	for host, s := range observed {
+		if doc, ok := docs.lookup(host); ok {
+			records = append(records, documentRecords(host, doc)...)
+			continue
+		}
+		purposes := sortedKeys(s.purposes)
+		if len(purposes) == 0 {
+			purposes = []string{noPurpose}
+		}
+		categories := sortedKeys(s.categories)
+		if len(categories) == 0 {
+			categories = []string{noCategory}
+		}
+		for _, p := range purposes {
+			for _, c := range categories {
+				records = append(records, record{
+					Controller:       undocumentedController,
+					Purpose:          p,
+					Service:          host,
+					Category:         c,
+					LegalBases:       sortedKeys(s.legalBases),
+					StorageDurations: sortedKeys(s.storageDurations),
+				})
+			}
+		}
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		a, b := records[i], records[j]
+		if a.Controller != b.Controller {
+			return a.Controller < b.Controller
+		}
+		if a.Purpose != b.Purpose {
+			return a.Purpose < b.Purpose
+		}
+		if a.Service != b.Service {
+			return a.Service < b.Service
+		}
+		return a.Category < b.Category
+	})
+	return records
+}
+
+func documentRecords(host string, doc *tilt.Document) []record {
+	controller := doc.Controller.Name
+	if controller == "" {
+		controller = unknownController
+	}
+	var countries []string
+	for _, t := range doc.ThirdCountryTransfers {
+		countries = append(countries, t.Country)
+	}
+
+	var records []record
+	for _, d := range doc.DataDisclosed {
+		r := record{
+			Controller:     controller,
+			Service:        host,
+			Category:       d.Category,
+			ThirdCountries: countries,
+		}
+		for _, l := range d.LegalBases {
+			r.LegalBases = append(r.LegalBases, l.Reference)
+		}
+		for _, s := range d.Storage {
+			for _, t := range s.Temporal {
+				r.StorageDurations = append(r.StorageDurations, t.TTL)
+			}
+		}
+		for _, rc := range d.Recipients {
+			name := rc.Name
+			if name == "" {
+				name = rc.Category
+			}
+			r.Recipients = append(r.Recipients, name)
+		}
+		if len(d.Purposes) == 0 {
+			r.Purpose = noPurpose
+			records = append(records, r)
+		}
+		for _, p := range d.Purposes {
+			r.Purpose = p.Purpose
+			records = append(records, r)
+		}
+	}
+	return records
+}
+
+func sortedKeys(m map[string]struct{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// writeCSV writes the register as CSV with a header row. Lists are joined with "; ".
+func writeCSV(w io.Writer, records []record) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"controller", "purpose", "service", "category", "legal_bases", "storage_durations", "recipients", "third_countries"})
+	for _, r := range records {
+		_ = cw.Write([]string{
+			r.Controller,
+			r.Purpose,
+			r.Service,
+			r.Category,
+			strings.Join(r.LegalBases, "; "),
+			strings.Join(r.StorageDurations, "; "),
+			strings.Join(r.Recipients, "; "),
+			strings.Join(r.ThirdCountries, "; "),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeMarkdown writes the register with a section per controller and purpose.
+func writeMarkdown(w io.Writer, records []record) error {
+	var b strings.Builder
+	b.WriteString("# Records of processing activities\n")
+	for i, r := range records {
+		if i == 0 || r.Controller != records[i-1].Controller {
+			fmt.Fprintf(&b, "\n## %s\n", r.Controller)
+		}
+		if i == 0 || r.Controller != records[i-1].Controller || r.Purpose != records[i-1].Purpose {
+			fmt.Fprintf(&b, "\n### %s\n\n", r.Purpose)
+			b.WriteString("| Service | Category | Legal bases | Storage | Recipients | Third countries |\n")
+			b.WriteString("| --- | --- | --- | --- | --- | --- |\n")
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s | %s | %s | %s |\n",
+			markdownCell(r.Service),
+			markdownCell(r.Category),
+			markdownCell(r.LegalBases...),
+			markdownCell(r.StorageDurations...),
+			markdownCell(r.Recipients...),
+			markdownCell(r.ThirdCountries...))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func markdownCell(values ...string) string {
+	s := strings.Join(values, ", ")
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.ReplaceAll(s, "\n", " ")
+}
@@ -1,14 +1,503 @@
 package transparencyprocessor
 
 import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mindtastic/opentelemetry-transparency-processor/internal/extractor"
 	"github.com/mindtastic/opentelemetry-transparency-processor/internal/filterconfig"
 	"go.opentelemetry.io/collector/config"
+	"go.opentelemetry.io/collector/config/confighttp"
 )
 
 type Config struct {
 	config.ProcessorSettings `mapstructure:",squash"` // squash ensures fields are correctly decoded in embedded struct
 	filterconfig.MatchConfig `mapstructure:",squash"`
 	ServiceMap               map[string]string `mapstructure:"serviceMap"`
+
+	// Cache configures how long fetched TILT attributes are kept before they are refreshed.
+	Cache CacheConfig `mapstructure:"cache"`
+
+	// Fetch configures the workers that fetch TILT documents off the span path.
+	Fetch FetchConfig `mapstructure:"fetch"`
+
+	// Client configures the HTTP client used by "http" sources to request
+	// TILT documents from the services.
+	Client ClientConfig `mapstructure:"client"`
+
+	// Sources lists where TILT documents are looked up, in order. The first
+	// source that has a document for a host and path is used.
+	Sources []SourceConfig `mapstructure:"sources"`
+
+	// Validation sets how documents that violate the TILT schema are handled.
+	Validation ValidationMode `mapstructure:"validation"`
+
+	// Output sets how the disclosed data categories are added to spans.
+	Output OutputMode `mapstructure:"output"`
+
+	// Meshes lists the service meshes whose proxies emit the spans, in order.
+	// Spans of resources that belong to none of them are not enriched.
+	Meshes []MeshConfig `mapstructure:"meshes"`
+
+	// Extractors lists how the host and path of a span are derived, in order.
+	// The first extractor whose attributes are present on the span or its
+	// resource is used, spans without any are not enriched.
+	//  http.host:      http.host with the span name as path.
+	//  http.url:       host and path of the URL in http.url.
+	//  http.target:    http.target with http.host, net.peer.name or server.address.
+	//  server.address: server.address and url.path.
+	//  net.peer.name:  net.peer.name with the span name as path.
+	//  rpc:            rpc.service and rpc.method.
+	Extractors []string `mapstructure:"extractors"`
+
+	// Routes maps hosts to route templates like /users/{id}. Paths are
+	// normalized to the matching template before the attributes are looked
+	// up, so that all concrete paths of a route share one cache entry and
+	// fetch. Templates are also read from the routes of fetched TILT
+	// documents, the configured ones are tried first.
+	Routes map[string][]string `mapstructure:"routes"`
+
+	// Policies are evaluated on every enriched span. The names of violated
+	// policies are added as tilt.violations and counted.
+	Policies []PolicyConfig `mapstructure:"policies"`
+
+	// Prefetch configures warming the cache when the processor starts.
+	Prefetch PrefetchConfig `mapstructure:"prefetch"`
+
+	// Transfers configures where the callees of spans are located. Spans to a
+	// callee outside the EU/EEA are marked with tilt.transfer.country, and
+	// with tilt.transfer.undeclared if the TILT document does not declare the
+	// transfer.
+	Transfers TransfersConfig `mapstructure:"transfers"`
+
+	// Redaction scrubs attributes of spans to services whose TILT document
+	// declares sensitive categories, before the spans are exported. Spans
+	// whose document is not known, because it is not cached yet, failed to
+	// fetch or the span matches no mesh or extractor, are only redacted by
+	// rules with fail_closed. Spans excluded by include and exclude are never
+	// redacted. All matching rules are applied in order.
+	Redaction []RedactionConfig `mapstructure:"redaction"`
+
+	// Server configures a local HTTP endpoint. Leave the endpoint empty to disable.
+	//  /ready:     200 once the prefetch finished and 503 before, for readiness probes.
+	//  /graph:     the observed data flows between services as JSON.
+	//  /graph.dot: the same as Graphviz DOT.
+	//  /cache:     the cached TILT documents in the cache snapshot format.
+	Server confighttp.HTTPServerSettings `mapstructure:"server"`
+
+	// Logs configures the enrichment of log records.
+	Logs LogsConfig `mapstructure:"logs"`
+}
+
+// PolicyConfig is a compliance rule checked against the TILT document of the
+// service a span is sent to, e.g. "health data may only flow to services
+// that declare the purpose therapy".
+type PolicyConfig struct {
+	// Name identifies the policy in tilt.violations and the violations metric.
+	Name string `mapstructure:"name"`
+
+	// When selects the spans the policy applies to.
+	When PolicyCondition `mapstructure:"when"`
+
+	// Require lists what the document must declare for the selected spans.
+	Require PolicyRequirement `mapstructure:"require"`
+}
+
+// PolicyCondition selects spans by the declarations of their document. All
+// specified conditions must hold.
+type PolicyCondition struct {
+	// Categories applies the policy to documents disclosing any of these
+	// categories. Categories are compared ignoring case, e.g. "health"
+	// matches "Health" but not "Health data". The requirements are checked
+	// per matching category.
+	Categories []string `mapstructure:"categories"`
+
+	// AutomatedDecisionMaking applies the policy to documents declaring
+	// automated decision making.
+	AutomatedDecisionMaking bool `mapstructure:"automated_decision_making"`
+}
+
+// PolicyRequirement lists declarations of which at least one each must be present.
+type PolicyRequirement struct {
+	// Purposes of which at least one must be declared.
+	Purposes []string `mapstructure:"purposes"`
+
+	// LegalBases of which at least one must be declared. A reference also
+	// satisfies its parents, e.g. GDPR-22-2-a satisfies GDPR-22. References
+	// are compared in their canonical form, so "Art. 22 GDPR" works as well.
+	LegalBases []string `mapstructure:"legal_bases"`
+}
+
+// PrefetchConfig configures which documents are fetched on start, before the
+// first span of a service arrives.
+type PrefetchConfig struct {
+	// Enabled fetches the root document of every host in ServiceMap. Spans
+	// and log records of a path whose own document is not cached yet are
+	// enriched with the root document of their host.
+	Enabled bool `mapstructure:"enabled"`
+
+	// Paths are fetched for every host in ServiceMap in addition to the root
+	// document. This is an optional field.
+	Paths []string `mapstructure:"paths"`
+}
+
+// TransfersConfig maps callees to countries. Countries are ISO 3166-1
+// alpha-2 codes like "US" or the English names of the EU/EEA member states
+// and of the countries with cloud regions, like "Germany" or "United States".
+type TransfersConfig struct {
+	// Regions maps cloud.region values, optionally prefixed with the
+	// cloud.provider as in "aws/us-east-1", to countries. It extends and
+	// overrides the built-in table of the regions of AWS, GCP and Azure.
+	Regions map[string]string `mapstructure:"regions"`
+
+	// Hosts maps hosts to countries, e.g. for external APIs that do not
+	// report a cloud.region. They take precedence over regions.
+	Hosts map[string]string `mapstructure:"hosts"`
+}
+
+// MeshConfig configures how the proxies of a service mesh are identified.
+type MeshConfig struct {
+	// Profile is one of "linkerd", "istio" or "none".
+	//  linkerd: linkerd.io/proxy-deployment, -statefulset or -daemonset.
+	//  istio:   istio.canonical_service or the pod of the Envoy node_id.
+	//  none:    every resource, service.name is kept as is.
+	Profile string `mapstructure:"profile"`
+
+	// WorkloadAttributes overrides the resource attributes that name the
+	// proxied workload. The first attribute present is used.
+	WorkloadAttributes []string `mapstructure:"workload_attributes"`
+
+	// ServiceNameFormat overrides the format service.name is rewritten to,
+	// with %s replaced by the workload, e.g. "%s-proxy".
+	ServiceNameFormat string `mapstructure:"service_name_format"`
+}
+
+// LogsConfig configures how log records are matched to TILT attributes.
+// Records are keyed by their http.host and http.target (or http.route)
+// attributes. Records without an http.host are enriched with the attributes of
+// the span they belong to, if that span passed a traces pipeline of the same
+// processor.
+type LogsConfig struct {
+	// SpanCacheSize is the number of recently processed spans that are
+	// remembered to link log records by their trace and span IDs.
+	SpanCacheSize int `mapstructure:"span_cache_size"`
+}
+
+// OutputMode describes how the disclosed data categories are added to spans.
+type OutputMode string
+
+const (
+	// OutputFlat adds one list attribute each for all categories, purposes,
+	// legal bases and storage durations of the document.
+	OutputFlat OutputMode = "flat"
+	// OutputIndexed adds the attributes of every category under its index,
+	// e.g. tilt.data_disclosed.0.category and tilt.data_disclosed.0.purposes.
+	OutputIndexed OutputMode = "indexed"
+	// OutputEvents adds one tilt.data_disclosed span event per category.
+	// Log records have no events and fall back to OutputIndexed.
+	OutputEvents OutputMode = "events"
+)
+
+// RedactionAction describes how attributes are redacted.
+type RedactionAction string
+
+const (
+	// RedactionDelete removes the attributes and query strings.
+	RedactionDelete RedactionAction = "delete"
+	// RedactionHash replaces values with their hex encoded HMAC-SHA256 under
+	// the HashKey of the rule.
+	RedactionHash RedactionAction = "hash"
+	// RedactionMask replaces values with "****".
+	RedactionMask RedactionAction = "mask"
+)
+
+// RedactionConfig is a rule that redacts span attributes if the TILT document
+// declares one of its categories.
+type RedactionConfig struct {
+	// Categories trigger the rule if a declared category contains one of
+	// them, ignoring case. Defaults to the special categories of Art. 9 GDPR
+	// like health, genetic or biometric data.
+	Categories []string `mapstructure:"categories"`
+
+	// Action is one of "delete", "hash" or "mask".
+	Action RedactionAction `mapstructure:"action"`
+
+	// HashKey is the secret key of the HMAC for the action "hash" and must be
+	// set for it. Plain hashes of user IDs or emails can be reversed with a
+	// dictionary, so hashing without a key is not allowed.
+	HashKey string `mapstructure:"hash_key"`
+
+	// Attributes are the span attributes to redact, e.g. enduser.id.
+	Attributes []string `mapstructure:"attributes"`
+
+	// QueryStrings redacts the query strings of http.url, http.target,
+	// url.full and url.query. Hash and mask keep the parameter names and only
+	// redact their values, parameters without a value are redacted as a whole.
+	QueryStrings bool `mapstructure:"query_strings"`
+
+	// FailClosed applies the rule to spans whose TILT document is not known,
+	// because it is not cached yet, could not be fetched or no host could be
+	// derived for the span.
+	FailClosed bool `mapstructure:"fail_closed"`
+}
+
+// ValidationMode describes how documents that violate the TILT schema are handled.
+type ValidationMode string
+
+const (
+	// ValidationReject treats invalid documents like failed fetches.
+	ValidationReject ValidationMode = "reject"
+	// ValidationEnrich uses invalid documents and adds the violations as tilt.validation_errors.
+	ValidationEnrich ValidationMode = "enrich"
+	// ValidationLog uses invalid documents and only logs the violations.
+	ValidationLog ValidationMode = "log"
+)
+
+// SourceConfig configures one source of TILT documents.
+type SourceConfig struct {
+	// Type is one of "http", "files" or "static".
+	//  http:   requests the document from the /tilt endpoint of the service.
+	//  files:  reads documents from a local directory.
+	//  static: uses the document given in the configuration.
+	Type string `mapstructure:"type"`
+
+	// FilesConfig configures a "files" source.
+	FilesConfig `mapstructure:",squash"`
+
+	// Hosts limits a "static" source to the given hosts. If empty, the
+	// document is used for every host, e.g. as an org-wide default.
+	Hosts []string `mapstructure:"hosts"`
+
+	// Document is the TILT document of a "static" source.
+	Document map[string]interface{} `mapstructure:"document"`
+}
+
+// FilesConfig configures a directory of TILT JSON documents. Changes to the
+// directory are picked up while the collector is running.
+type FilesConfig struct {
+	// Directory containing the TILT documents. Without a manifest, the document
+	// for a host is read from <host>.json. Leave empty to disable.
+	Directory string `mapstructure:"directory"`
+
+	// Manifest is the name of a JSON file in Directory that maps hosts to
+	// document file names. This is an optional field.
+	Manifest string `mapstructure:"manifest"`
+}
+
+// ClientConfig configures requests to the services' /tilt endpoints.
+// The endpoint setting is not supported, hosts are taken from the spans.
+//
+// Requests go through the egress proxy given by the HTTP_PROXY, HTTPS_PROXY
+// and NO_PROXY environment variables of the collector, there is no setting
+// for it.
+type ClientConfig struct {
+	confighttp.HTTPClientSettings `mapstructure:",squash"`
+
+	// Scheme is the URL scheme used to reach the services, "http" or "https".
+	Scheme string `mapstructure:"scheme"`
+}
+
+// CacheConfig configures expiry and background refresh of the TILT attributes cache.
+type CacheConfig struct {
+	// TTL is the time after which cached attributes are considered stale.
+	// Stale attributes are still used for spans while a refresh is running.
+	// Entries that no span used for longer than TTL are evicted instead of
+	// refreshed.
+	TTL time.Duration `mapstructure:"ttl"`
+
+	// RefreshAhead is the window before expiry in which the background refresher
+	// re-fetches an entry, so that hot entries rarely go stale.
+	RefreshAhead time.Duration `mapstructure:"refresh_ahead"`
+
+	// RefreshInterval is how often the background refresher scans the cache.
+	RefreshInterval time.Duration `mapstructure:"refresh_interval"`
+
+	// FailureBackoff configures when a failed fetch is retried. Until then,
+	// the key is served from a negative cache entry (or its last known attributes).
+	// Failed keys are not refreshed in the background, only the next span
+	// after the interval retries them.
+	FailureBackoff BackoffConfig `mapstructure:"failure_backoff"`
+
+	// Snapshot configures a file the cache is persisted to, so that spans
+	// are enriched right after a restart, even if services are unreachable.
+	Snapshot SnapshotConfig `mapstructure:"snapshot"`
+}
+
+// SnapshotConfig configures the on-disk snapshot of the attributes cache.
+type SnapshotConfig struct {
+	// Path of the snapshot file. It is loaded on start and written every
+	// Interval and on shutdown. Leave empty to disable.
+	Path string `mapstructure:"path"`
+
+	// Interval is how often the snapshot is written.
+	Interval time.Duration `mapstructure:"interval"`
+}
+
+// FetchConfig configures the pool of workers fetching TILT documents.
+type FetchConfig struct {
+	// Workers is the number of concurrent fetches.
+	Workers int `mapstructure:"workers"`
+
+	// QueueSize is the number of fetches that can be waiting for a worker.
+	// Cache misses are dropped while the queue is full and retried with the
+	// next span.
+	QueueSize int `mapstructure:"queue_size"`
+}
+
+// BackoffConfig configures randomized exponential backoff.
+type BackoffConfig struct {
+	// InitialInterval is the wait time after the first failure.
+	InitialInterval time.Duration `mapstructure:"initial_interval"`
+
+	// MaxInterval is the upper bound of the wait time.
+	MaxInterval time.Duration `mapstructure:"max_interval"`
+
+	// Multiplier is applied to the wait time after each consecutive failure.
+	Multiplier float64 `mapstructure:"multiplier"`
+
+	// RandomizationFactor spreads retries of different keys over
+	// [interval * (1 - factor), interval * (1 + factor)].
+	RandomizationFactor float64 `mapstructure:"randomization_factor"`
 }
 
 var _ config.Processor = (*Config)(nil)
+
+// Validate checks if the processor configuration is valid.
+func (cfg *Config) Validate() error {
+	if cfg.Cache.TTL <= 0 {
+		return errors.New("cache.ttl must be positive")
+	}
+	if cfg.Cache.RefreshAhead < 0 || cfg.Cache.RefreshAhead >= cfg.Cache.TTL {
+		return errors.New("cache.refresh_ahead must be between 0 and cache.ttl")
+	}
+	if cfg.Cache.RefreshInterval <= 0 {
+		return errors.New("cache.refresh_interval must be positive")
+	}
+	if cfg.Cache.Snapshot.Path != "" && cfg.Cache.Snapshot.Interval <= 0 {
+		return errors.New("cache.snapshot.interval must be positive")
+	}
+	if cfg.Fetch.Workers <= 0 {
+		return errors.New("fetch.workers must be positive")
+	}
+	if cfg.Fetch.QueueSize <= 0 {
+		return errors.New("fetch.queue_size must be positive")
+	}
+	if len(cfg.Sources) == 0 {
+		return errors.New("at least one source must be specified")
+	}
+	for i, s := range cfg.Sources {
+		if err := s.Validate(); err != nil {
+			return fmt.Errorf("sources[%d]: %w", i, err)
+		}
+	}
+	switch cfg.Validation {
+	case ValidationReject, ValidationEnrich, ValidationLog:
+	default:
+		return fmt.Errorf("unknown validation mode %q, valid modes are: %v", cfg.Validation, []ValidationMode{ValidationReject, ValidationEnrich, ValidationLog})
+	}
+	switch cfg.Output {
+	case OutputFlat, OutputIndexed, OutputEvents:
+	default:
+		return fmt.Errorf("unknown output mode %q, valid modes are: %v", cfg.Output, []OutputMode{OutputFlat, OutputIndexed, OutputEvents})
+	}
+	if len(cfg.Meshes) == 0 {
+		return errors.New("at least one mesh must be specified")
+	}
+	for i, m := range cfg.Meshes {
+		if err := m.Validate(); err != nil {
+			return fmt.Errorf("meshes[%d]: %w", i, err)
+		}
+	}
+	if len(cfg.Extractors) == 0 {
+		return errors.New("at least one extractor must be specified")
+	}
+	for _, e := range cfg.Extractors {
+		if !extractor.Known(e) {
+			return fmt.Errorf("unknown extractor %q, valid extractors are: %v", e, extractor.Default)
+		}
+	}
+	names := make(map[string]struct{}, len(cfg.Policies))
+	for i, p := range cfg.Policies {
+		if err := p.Validate(); err != nil {
+			return fmt.Errorf("policies[%d]: %w", i, err)
+		}
+		if _, ok := names[p.Name]; ok {
+			return fmt.Errorf("policies[%d]: duplicate name %q", i, p.Name)
+		}
+		names[p.Name] = struct{}{}
+	}
+	for i, r := range cfg.Redaction {
+		if err := r.Validate(); err != nil {
+			return fmt.Errorf("redaction[%d]: %w", i, err)
+		}
+	}
+	if err := cfg.Transfers.Validate(); err != nil {
+		return fmt.Errorf("transfers: %w", err)
+	}
+	if cfg.Logs.SpanCacheSize <= 0 {
+		return errors.New("logs.span_cache_size must be positive")
+	}
+	if err := cfg.Client.Validate(); err != nil {
+		return err
+	}
+	return cfg.Cache.FailureBackoff.Validate()
+}
+
+// Validate checks if the source configuration is valid.
+func (cfg *SourceConfig) Validate() error {
+	switch cfg.Type {
+	case sourceTypeHTTP:
+	case sourceTypeFiles:
+		if cfg.Directory == "" {
+			return errors.New("directory must be specified for files sources")
+		}
+	case sourceTypeStatic:
+		if len(cfg.Document) == 0 {
+			return errors.New("document must be specified for static sources")
+		}
+	default:
+		return fmt.Errorf("unknown type %q, valid types are: %v", cfg.Type, []string{sourceTypeHTTP, sourceTypeFiles, sourceTypeStatic})
+	}
+	return nil
+}
+
+// Validate checks if the mesh configuration is valid.
+func (cfg *MeshConfig) Validate() error {
+	if _, ok := meshProfiles[cfg.Profile]; !ok {
+		return fmt.Errorf("unknown profile %q, valid profiles are: %v", cfg.Profile, []string{meshProfileLinkerd, meshProfileIstio, meshProfileNone})
+	}
+	if cfg.ServiceNameFormat != "" && strings.Count(cfg.ServiceNameFormat, "%s") != 1 {
+		return fmt.Errorf("service_name_format must contain %%s exactly once, got %q", cfg.ServiceNameFormat)
+	}
+	return nil
+}
+
+// Validate checks if the client configuration is valid.
+func (cfg *ClientConfig) Validate() error {
+	if cfg.Endpoint != "" {
+		return errors.New("client.endpoint is not supported, hosts are taken from the spans")
+	}
+	if cfg.Scheme != "http" && cfg.Scheme != "https" {
+		return fmt.Errorf("client.scheme must be %q or %q, got %q", "http", "https", cfg.Scheme)
+	}
+	return nil
+}
+
+// Validate checks if the backoff configuration is valid.
+func (cfg *BackoffConfig) Validate() error {
+	if cfg.InitialInterval <= 0 {
+		return errors.New("failure_backoff.initial_interval must be positive")
+	}
+	if cfg.MaxInterval < cfg.InitialInterval {
+		return errors.New("failure_backoff.max_interval must not be less than initial_interval")
+	}
+	if cfg.Multiplier < 1 {
+		return errors.New("failure_backoff.multiplier must be at least 1")
+	}
+	if cfg.RandomizationFactor < 0 || cfg.RandomizationFactor > 1 {
+		return errors.New("failure_backoff.randomization_factor must be between 0 and 1")
+	}
+	return nil
+}
@@ -2,26 +2,70 @@ package transparencyprocessor
 
 import (
 	"context"
+	"github.com/mindtastic/opentelemetry-transparency-processor/internal/extractor"
+	"github.com/mindtastic/opentelemetry-transparency-processor/internal/filterlog"
+	"github.com/mindtastic/opentelemetry-transparency-processor/internal/filtermetric"
 	"github.com/mindtastic/opentelemetry-transparency-processor/internal/filterspan"
+	"go.opencensus.io/stats/view"
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/config"
+	"go.opentelemetry.io/collector/config/confighttp"
 	"go.opentelemetry.io/collector/consumer"
 	"go.opentelemetry.io/collector/processor/processorhelper"
+	"sync"
+	"time"
 )
 
 const typeStr = "transparency"
 
 func NewFactory() component.ProcessorFactory {
+	// TODO: find a more appropriate way to get this done, as we are swallowing the error here
+	_ = view.Register(metricViews()...)
+
 	return component.NewProcessorFactory(
 		typeStr,
 		createDefaultConfig,
 		component.WithTracesProcessor(createTracesProcessor),
+		component.WithLogsProcessor(createLogsProcessor),
+		component.WithMetricsProcessor(createMetricsProcessor),
 	)
 }
 
 func createDefaultConfig() config.Processor {
+	clientSettings := confighttp.NewDefaultHTTPClientSettings()
+	clientSettings.Timeout = 5 * time.Second
 	return &Config{
 		ProcessorSettings: config.NewProcessorSettings(config.NewComponentID(typeStr)),
+		Cache: CacheConfig{
+			TTL:             5 * time.Minute,
+			RefreshAhead:    30 * time.Second,
+			RefreshInterval: 10 * time.Second,
+			FailureBackoff: BackoffConfig{
+				InitialInterval:     5 * time.Second,
+				MaxInterval:         5 * time.Minute,
+				Multiplier:          2,
+				RandomizationFactor: 0.5,
+			},
+			Snapshot: SnapshotConfig{
+				Interval: time.Minute,
+			},
+		},
+		Fetch: FetchConfig{
+			Workers:   4,
+			QueueSize: 1000,
+		},
+		Client: ClientConfig{
+			HTTPClientSettings: clientSettings,
+			Scheme:             "http",
+		},
+		Sources:    []SourceConfig{{Type: sourceTypeHTTP}},
+		Validation: ValidationLog,
+		Output:     OutputFlat,
+		Meshes:     []MeshConfig{{Profile: meshProfileLinkerd}},
+		Extractors: append([]string(nil), extractor.Default...),
+		Logs: LogsConfig{
+			SpanCacheSize: 10000,
+		},
 	}
 }
 
@@ -35,9 +79,96 @@ func createTracesProcessor(_ context.Context, set component.ProcessorCreateSetti
 	if err != nil {
 		return nil, err
 	}
+	tp, err := sharedProcessor(set, oCfg)
+	if err != nil {
+		return nil, err
+	}
+	tp.include = include
+	tp.exclude = exclude
 	return processorhelper.NewTracesProcessor(
 		cfg, nextConsumer,
-		newTransparencyProcessor(set, include, exclude, oCfg.ServiceMap).processTraces,
+		tp.processTraces,
+		processorhelper.WithCapabilities(processorCapabilities),
+		processorhelper.WithStart(tp.start),
+		processorhelper.WithShutdown(tp.shutdown),
+	)
+}
+
+func createLogsProcessor(_ context.Context, set component.ProcessorCreateSettings, cfg config.Processor, nextConsumer consumer.Logs) (component.LogsProcessor, error) {
+	oCfg := cfg.(*Config)
+	include, err := filterlog.NewMatcher(oCfg.Include)
+	if err != nil {
+		return nil, err
+	}
+	exclude, err := filterlog.NewMatcher(oCfg.Exclude)
+	if err != nil {
+		return nil, err
+	}
+	tp, err := sharedProcessor(set, oCfg)
+	if err != nil {
+		return nil, err
+	}
+	tp.logInclude = include
+	tp.logExclude = exclude
+	return processorhelper.NewLogsProcessor(
+		cfg, nextConsumer,
+		tp.processLogs,
+		processorhelper.WithCapabilities(processorCapabilities),
+		processorhelper.WithStart(tp.start),
+		processorhelper.WithShutdown(tp.shutdown),
+	)
+}
+
+func createMetricsProcessor(_ context.Context, set component.ProcessorCreateSettings, cfg config.Processor, nextConsumer consumer.Metrics) (component.MetricsProcessor, error) {
+	oCfg := cfg.(*Config)
+	include, err := filtermetric.NewMatcher(oCfg.Include)
+	if err != nil {
+		return nil, err
+	}
+	exclude, err := filtermetric.NewMatcher(oCfg.Exclude)
+	if err != nil {
+		return nil, err
+	}
+	tp, err := sharedProcessor(set, oCfg)
+	if err != nil {
+		return nil, err
+	}
+	tp.metricInclude = include
+	tp.metricExclude = exclude
+	return processorhelper.NewMetricsProcessor(
+		cfg, nextConsumer,
+		tp.processMetrics,
 		processorhelper.WithCapabilities(processorCapabilities),
+		processorhelper.WithStart(tp.start),
+		processorhelper.WithShutdown(tp.shutdown),
 	)
 }
+
+// processors holds one processor per configuration, shared by its traces,
+// logs and metrics pipelines. All three use the same cache, and log records can
+// be linked to the spans that passed the traces pipeline.
+var processors = struct {
+	sync.Mutex
+	m map[*Config]*transparencyProcessor
+}{m: make(map[*Config]*transparencyProcessor)}
+
+// sharedProcessor returns the processor for cfg, creating it on first use.
+// The processor is released again when it is shut down.
+func sharedProcessor(set component.ProcessorCreateSettings, cfg *Config) (*transparencyProcessor, error) {
+	processors.Lock()
+	defer processors.Unlock()
+	if tp, ok := processors.m[cfg]; ok {
+		return tp, nil
+	}
+	tp, err := newTransparencyProcessor(set, cfg)
+	if err != nil {
+		return nil, err
+	}
+	tp.release = func() {
+		processors.Lock()
+		defer processors.Unlock()
+		delete(processors.m, cfg)
+	}
+	processors.m[cfg] = tp
+	return tp, nil
+}
@@ -0,0 +1,53 @@
+package transparencyprocessor
+
+import (
+	"sync"
+)
+
+// fetchRequest asks a worker to fetch the attributes for host and path.
+type fetchRequest struct {
+	key  string
+	host string
+	path string
+}
+
+// fetchQueue is a bounded queue of fetch requests. A key is queued at most
+// once until a worker finished fetching it, so concurrent misses for the same
+// key result in a single request.
+type fetchQueue struct {
+	mu      sync.Mutex
+	pending map[string]struct{}
+	queue   chan fetchRequest
+}
+
+func newFetchQueue(size int) *fetchQueue {
+	return &fetchQueue{
+		pending: make(map[string]struct{}),
+		queue:   make(chan fetchRequest, size),
+	}
+}
+
+// enqueue queues a fetch for host and path unless one for key is already
+// pending. It never blocks and returns false if the queue is full.
+func (q *fetchQueue) enqueue(key, host, path string) bool {
+	r := fetchRequest{key: key, host: host, path: path}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if _, ok := q.pending[r.key]; ok {
+		return true
+	}
+	select {
+	case q.queue <- r:
+		q.pending[r.key] = struct{}{}
+		return true
+	default:
+		return false
+	}
+}
+
+// done marks the fetch for key as finished, so it can be queued again.
+func (q *fetchQueue) done(key string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.pending, key)
+}
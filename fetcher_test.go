@@ -0,0 +1,24 @@
+package transparencyprocessor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFetchQueue(t *testing.T) {
+	q := newFetchQueue(2)
+
+	assert.True(t, q.enqueue("host/a", "host", "a"))
+	assert.True(t, q.enqueue("host/a", "host", "a"), "pending key is not queued again")
+	assert.Len(t, q.queue, 1)
+
+	assert.True(t, q.enqueue("host/b", "host", "b"))
+	assert.False(t, q.enqueue("host/c", "host", "c"), "full queue drops requests")
+
+	r := <-q.queue
+	assert.Equal(t, fetchRequest{key: "host/a", host: "host", path: "a"}, r)
+	q.done(r.key)
+	assert.True(t, q.enqueue("host/a", "host", "a"))
+	assert.Len(t, q.queue, 2)
+}
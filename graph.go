@@ -0,0 +1,133 @@
+package transparencyprocessor
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// flowGraph aggregates which data categories and purposes flow from which
+// source service to which destination, as observed on enriched spans.
+type flowGraph struct {
+	mu    sync.Mutex
+	edges map[flowKey]*flowEdge
+	now   func() time.Time
+}
+
+type flowKey struct {
+	source      string
+	destination string
+}
+
+type flowEdge struct {
+	categories map[string]struct{}
+	purposes   map[string]struct{}
+	spans      int64
+	firstSeen  time.Time
+	lastSeen   time.Time
+}
+
+func newFlowGraph() *flowGraph {
+	return &flowGraph{edges: make(map[flowKey]*flowEdge), now: time.Now}
+}
+
+// add records a span from source to destination with attr.
+func (g *flowGraph) add(source, destination string, attr tiltAttributes) {
+	now := g.now()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	k := flowKey{source: source, destination: destination}
+	e, ok := g.edges[k]
+	if !ok {
+		e = &flowEdge{categories: make(map[string]struct{}), purposes: make(map[string]struct{}), firstSeen: now}
+		g.edges[k] = e
+	}
+	for _, c := range attr.categories {
+		e.categories[c] = struct{}{}
+	}
+	for _, p := range attr.puproses {
+		e.purposes[p] = struct{}{}
+	}
+	e.spans++
+	e.lastSeen = now
+}
+
+// flow is the JSON representation of an edge of the graph.
+type flow struct {
+	Source      string    `json:"source"`
+	Destination string    `json:"destination"`
+	Categories  []string  `json:"categories"`
+	Purposes    []string  `json:"purposes"`
+	Spans       int64     `json:"spans"`
+	FirstSeen   time.Time `json:"first_seen"`
+	LastSeen    time.Time `json:"last_seen"`
+}
+
+// flows returns the edges sorted by source and destination.
+func (g *flowGraph) flows() []flow {
+	g.mu.Lock()
+	flows := make([]flow, 0, len(g.edges))
+	for k, e := range g.edges {
+		flows = append(flows, flow{
+			Source:      k.source,
+			Destination: k.destination,
+			Categories:  sortedKeys(e.categories),
+			Purposes:    sortedKeys(e.purposes),
+			Spans:       e.spans,
+			FirstSeen:   e.firstSeen,
+			LastSeen:    e.lastSeen,
+		})
+	}
+	g.mu.Unlock()
+	sort.Slice(flows, func(i, j int) bool {
+		if flows[i].Source != flows[j].Source {
+			return flows[i].Source < flows[j].Source
+		}
+		return flows[i].Destination < flows[j].Destination
+	})
+	return flows
+}
+
+func sortedKeys(m map[string]struct{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// writeDOT writes the graph in the Graphviz DOT language. Edges are labeled
+// with their categories and, on a second line, their purposes.
+func (g *flowGraph) writeDOT(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("digraph tilt {\n")
+	for _, f := range g.flows() {
+		label := strings.Join(f.Categories, ", ")
+		if len(f.Purposes) > 0 {
+			label += "\n" + strings.Join(f.Purposes, ", ")
+		}
+		fmt.Fprintf(&b, "  %s -> %s [label=%s];\n", strconv.Quote(f.Source), strconv.Quote(f.Destination), strconv.Quote(label))
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func (g *flowGraph) handleJSON(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(struct {
+		Flows []flow `json:"flows"`
+	}{Flows: g.flows()})
+}
+
+func (g *flowGraph) handleDOT(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/vnd.graphviz")
+	_ = g.writeDOT(w)
+}
@@ -0,0 +1,112 @@
+package transparencyprocessor
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+)
+
+func TestFlowGraph(t *testing.T) {
+	g := newFlowGraph()
+	clock := &fakeClock{t: time.Unix(1600000000, 0).UTC()}
+	g.now = clock.now
+
+	g.add("web-proxy", "users", tiltAttributes{categories: []string{"email"}, puproses: []string{"newsletter"}})
+	clock.advance(time.Minute)
+	g.add("web-proxy", "users", tiltAttributes{categories: []string{"health", "email"}, puproses: []string{"therapy"}})
+	g.add("api-proxy", "orders", tiltAttributes{categories: []string{"address"}})
+
+	assert.Equal(t, []flow{
+		{
+			Source:      "api-proxy",
+			Destination: "orders",
+			Categories:  []string{"address"},
+			Purposes:    []string{},
+			Spans:       1,
+			FirstSeen:   clock.t,
+			LastSeen:    clock.t,
+		},
+		{
+			Source:      "web-proxy",
+			Destination: "users",
+			Categories:  []string{"email", "health"},
+			Purposes:    []string{"newsletter", "therapy"},
+			Spans:       2,
+			FirstSeen:   clock.t.Add(-time.Minute),
+			LastSeen:    clock.t,
+		},
+	}, g.flows())
+
+	var b strings.Builder
+	require.NoError(t, g.writeDOT(&b))
+	assert.Equal(t, `digraph tilt {
+  "api-proxy" -> "orders" [label="address"];
+  "web-proxy" -> "users" [label="email, health\nnewsletter, therapy"];
+}
+`, b.String())
+}
+
+func TestProcessTracesGraph(t *testing.T) {
+	ln, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	endpoint := ln.Addr().String()
+	require.NoError(t, ln.Close())
+
+	srv := newTiltServer(t, testTiltDocument)
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.ServiceMap = map[string]string{"testHost": srv.Listener.Addr().String()}
+	cfg.Server.Endpoint = endpoint
+	tp, err := factory.CreateTracesProcessor(context.Background(), componenttest.NewNopProcessorCreateSettings(), cfg, consumertest.NewNop())
+	require.NoError(t, err)
+	tp = startedProcessor(t, tp)
+
+	runIndividualTestCase(t, testCase{
+		name:               "graph",
+		serviceName:        "linkerd-proxy",
+		resourceAttributes: map[string]interface{}{"linkerd.io/proxy-deployment": "linkerd"},
+		inputAttributes:    map[string]interface{}{"http.host": "testHost"},
+		expectedAttributes: testEnrichedAttributes(map[string]interface{}{
+			"http.host": "testHost",
+		}),
+	}, tp)
+
+	resp, err := http.Get("http://" + endpoint + "/graph")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var graph struct {
+		Flows []flow `json:"flows"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&graph))
+	require.Len(t, graph.Flows, 1)
+	assert.Equal(t, "linkerd-proxy", graph.Flows[0].Source, "source is the rewritten service name")
+	assert.Equal(t, "testHost", graph.Flows[0].Destination)
+	assert.Equal(t, []string{"testing"}, graph.Flows[0].Categories)
+	assert.Equal(t, []string{"testing purposes"}, graph.Flows[0].Purposes)
+
+	dot, err := http.Get("http://" + endpoint + "/graph.dot")
+	require.NoError(t, err)
+	defer dot.Body.Close()
+	assert.Equal(t, "text/vnd.graphviz", dot.Header.Get("Content-Type"))
+	body, err := io.ReadAll(dot.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `"linkerd-proxy" -> "testHost" [label="testing\ntesting purposes"];`)
+
+	cache, err := http.Get("http://" + endpoint + "/cache")
+	require.NoError(t, err)
+	defer cache.Body.Close()
+	var snap snapshot
+	require.NoError(t, json.NewDecoder(cache.Body).Decode(&snap))
+	require.Len(t, snap.Entries, 1)
+	assert.Equal(t, "testHost", snap.Entries[0].Host)
+}
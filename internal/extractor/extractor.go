@@ -0,0 +1,151 @@
+// Package extractor derives the host and path TILT documents are looked up
+// for from the attributes of a span. It is shared by the processor and the
+// tiltreport command.
+package extractor
+
+import (
+	"net"
+	"net/url"
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	conventions "go.opentelemetry.io/collector/semconv/v1.6.1"
+)
+
+// Names of the extractors.
+const (
+	HTTPHost      = "http.host"
+	HTTPURL       = "http.url"
+	HTTPTarget    = "http.target"
+	ServerAddress = "server.address"
+	NetPeerName   = "net.peer.name"
+	RPC           = "rpc"
+
+	// Attributes of semantic conventions newer than the version used by the collector.
+	attrServerAddress = "server.address"
+	attrServerPort    = "server.port"
+	attrURLPath       = "url.path"
+)
+
+// extractor derives the host and path TILT documents are looked up for from
+// the attributes of a span and its resource. ok is false if the attributes
+// the extractor needs are missing.
+type extractor func(s Span) (host, path string, ok bool)
+
+// Span holds the name and attributes of a span. Attributes are looked up
+// in the span first, then in its resource.
+type Span struct {
+	Name     string
+	Attrs    pcommon.Map
+	Resource pcommon.Map
+}
+
+func (s Span) get(key string) (string, bool) {
+	v, ok := s.Attrs.Get(key)
+	if !ok {
+		v, ok = s.Resource.Get(key)
+	}
+	if !ok || v.AsString() == "" {
+		return "", false
+	}
+	return v.AsString(), true
+}
+
+// hostPort returns the value of hostKey, joined with the value of portKey if present.
+func (s Span) hostPort(hostKey, portKey string) (string, bool) {
+	host, ok := s.get(hostKey)
+	if !ok {
+		return "", false
+	}
+	if port, ok := s.get(portKey); ok {
+		return net.JoinHostPort(host, port), true
+	}
+	return host, true
+}
+
+// Default lists all extractors. http.host comes first to keep the keys of
+// spans that were enriched before the other extractors existed.
+var Default = []string{
+	HTTPHost,
+	HTTPURL,
+	HTTPTarget,
+	ServerAddress,
+	NetPeerName,
+	RPC,
+}
+
+var extractors = map[string]extractor{
+	// http.host with the span name as path.
+	HTTPHost: func(s Span) (string, string, bool) {
+		host, ok := s.get(conventions.AttributeHTTPHost)
+		return host, s.Name, ok
+	},
+	// The host and path of the full URL in http.url.
+	HTTPURL: func(s Span) (string, string, bool) {
+		raw, ok := s.get(conventions.AttributeHTTPURL)
+		if !ok {
+			return "", "", false
+		}
+		u, err := url.Parse(raw)
+		if err != nil || u.Host == "" {
+			return "", "", false
+		}
+		return u.Host, u.Path, true
+	},
+	// http.target without query, with the host from http.host, net.peer.name or server.address.
+	HTTPTarget: func(s Span) (string, string, bool) {
+		target, ok := s.get(conventions.AttributeHTTPTarget)
+		if !ok {
+			return "", "", false
+		}
+		host, ok := s.get(conventions.AttributeHTTPHost)
+		if !ok {
+			host, ok = s.hostPort(conventions.AttributeNetPeerName, conventions.AttributeNetPeerPort)
+		}
+		if !ok {
+			host, ok = s.hostPort(attrServerAddress, attrServerPort)
+		}
+		target, _, _ = strings.Cut(target, "?")
+		return host, target, ok
+	},
+	// server.address and url.path of the stable HTTP semantic conventions.
+	ServerAddress: func(s Span) (string, string, bool) {
+		host, ok := s.hostPort(attrServerAddress, attrServerPort)
+		if !ok {
+			return "", "", false
+		}
+		p, ok := s.get(attrURLPath)
+		return host, p, ok
+	},
+	// net.peer.name with the span name as path.
+	NetPeerName: func(s Span) (string, string, bool) {
+		host, ok := s.hostPort(conventions.AttributeNetPeerName, conventions.AttributeNetPeerPort)
+		return host, s.Name, ok
+	},
+	// rpc.service as host and rpc.method as path, e.g. for gRPC.
+	RPC: func(s Span) (string, string, bool) {
+		service, ok := s.get(conventions.AttributeRPCService)
+		if !ok {
+			return "", "", false
+		}
+		method, ok := s.get(conventions.AttributeRPCMethod)
+		return service, method, ok
+	},
+}
+
+// Known reports whether name is an extractor.
+func Known(name string) bool {
+	_, ok := extractors[name]
+	return ok
+}
+
+// HostPath returns the host and path of the first of the named extractors
+// that applies to s.
+func HostPath(names []string, s Span) (string, string, bool) {
+	for _, n := range names {
+		if host, path, ok := extractors[n](s); ok {
+			return host, path, true
+		}
+	}
+	return "", "", false
+}
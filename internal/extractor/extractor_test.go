@@ -0,0 +1,104 @@
+package extractor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+func TestHostPath(t *testing.T) {
+	testCases := []struct {
+		name       string
+		extractors []string
+		attrs      map[string]interface{}
+		resource   map[string]interface{}
+		wantHost   string
+		wantPath   string
+		wantOK     bool
+	}{
+		{
+			name:     "http.host",
+			attrs:    map[string]interface{}{"http.host": "users:8080", "http.url": "http://other/path"},
+			wantHost: "users:8080",
+			wantPath: "span",
+			wantOK:   true,
+		},
+		{
+			name:     "http.host from resource",
+			resource: map[string]interface{}{"http.host": "users"},
+			wantHost: "users",
+			wantPath: "span",
+			wantOK:   true,
+		},
+		{
+			name:     "http.url",
+			attrs:    map[string]interface{}{"http.url": "https://users:8443/api/users?page=2"},
+			wantHost: "users:8443",
+			wantPath: "/api/users",
+			wantOK:   true,
+		},
+		{
+			name:     "http.target with net.peer.name",
+			attrs:    map[string]interface{}{"http.target": "/api/users?page=2", "net.peer.name": "users", "net.peer.port": 8080},
+			wantHost: "users:8080",
+			wantPath: "/api/users",
+			wantOK:   true,
+		},
+		{
+			name:     "server.address",
+			attrs:    map[string]interface{}{"server.address": "users", "url.path": "/api/users"},
+			wantHost: "users",
+			wantPath: "/api/users",
+			wantOK:   true,
+		},
+		{
+			name:     "net.peer.name",
+			attrs:    map[string]interface{}{"net.peer.name": "users"},
+			wantHost: "users",
+			wantPath: "span",
+			wantOK:   true,
+		},
+		{
+			name:     "rpc",
+			attrs:    map[string]interface{}{"rpc.service": "shop.Users", "rpc.method": "Get"},
+			wantHost: "shop.Users",
+			wantPath: "Get",
+			wantOK:   true,
+		},
+		{
+			name:       "order",
+			extractors: []string{HTTPURL, HTTPHost},
+			attrs:      map[string]interface{}{"http.host": "users", "http.url": "http://other/path"},
+			wantHost:   "other",
+			wantPath:   "/path",
+			wantOK:     true,
+		},
+		{
+			name:       "disabled",
+			extractors: []string{HTTPHost},
+			attrs:      map[string]interface{}{"http.url": "http://other/path"},
+		},
+		{
+			name:  "nothing to extract",
+			attrs: map[string]interface{}{"http.url": "/relative", "url.path": "/path"},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			names := tt.extractors
+			if names == nil {
+				names = Default
+			}
+			host, path, ok := HostPath(names, Span{
+				Name:     "span",
+				Attrs:    pcommon.NewMapFromRaw(tt.attrs),
+				Resource: pcommon.NewMapFromRaw(tt.resource),
+			})
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.wantHost, host)
+			assert.Equal(t, tt.wantPath, path)
+		})
+	}
+}
@@ -77,7 +77,7 @@ type MatchProperties struct {
 	// For logs, one of LogNames, Attributes, Resources or Libraries must be specified with a
 	// non-empty value for a valid configuration.
 
-	// For metrics, one of MetricNames, Expressions, or ResourceAttributes must be specified with a
+	// For metrics, one of MetricNames, Resources or Libraries must be specified with a
 	// non-empty value for a valid configuration.
 
 	// Services specify the list of items to match service name against.
@@ -151,6 +151,27 @@ func (mp *MatchProperties) ValidateForLogs() error {
 	return nil
 }
 
+// ValidateForMetrics validates properties for metrics.
+func (mp *MatchProperties) ValidateForMetrics() error {
+	if len(mp.SpanNames) > 0 || len(mp.Services) > 0 {
+		return errors.New("neither services nor span_names should be specified for metrics")
+	}
+
+	if len(mp.LogBodies) > 0 || len(mp.LogSeverityTexts) > 0 {
+		return errors.New("neither log_bodies nor log_severity_texts should be specified for metrics")
+	}
+
+	if len(mp.Attributes) > 0 {
+		return errors.New("attributes should not be specified for metrics, use resources instead")
+	}
+
+	if len(mp.MetricNames) == 0 && len(mp.Libraries) == 0 && len(mp.Resources) == 0 {
+		return errors.New(`at least one of "metric_names", "libraries" or "resources" field must be specified`)
+	}
+
+	return nil
+}
+
 // Attribute specifies the attribute key and optional value to match against.
 type Attribute struct {
 	// Key specifies the attribute key.
@@ -0,0 +1,124 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filterlog // import "github.com/open-telemetry/opentelemetry-collector-contrib/internal/coreinternal/processor/filterlog"
+
+import (
+	"fmt"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+
+	"github.com/mindtastic/opentelemetry-transparency-processor/internal/filterconfig"
+	"github.com/mindtastic/opentelemetry-transparency-processor/internal/filtermatcher"
+	"github.com/mindtastic/opentelemetry-transparency-processor/internal/filterset"
+)
+
+// Matcher is an interface that allows matching a log record against a
+// configuration of a match.
+// TODO: Modify Matcher to invoke both the include and exclude properties so
+// calling processors will always have the same logic.
+type Matcher interface {
+	MatchLogRecord(lr plog.LogRecord, resource pcommon.Resource, library pcommon.InstrumentationScope) bool
+}
+
+// propertiesMatcher allows matching a log record against various log record properties.
+type propertiesMatcher struct {
+	filtermatcher.PropertiesMatcher
+
+	// log bodies to compare to.
+	bodyFilters filterset.FilterSet
+
+	// log severity texts to compare to
+	severityTextFilters filterset.FilterSet
+}
+
+// NewMatcher creates a LogRecord Matcher that matches based on the given MatchProperties.
+func NewMatcher(mp *filterconfig.MatchProperties) (Matcher, error) {
+	if mp == nil {
+		return nil, nil
+	}
+
+	if err := mp.ValidateForLogs(); err != nil {
+		return nil, err
+	}
+
+	rm, err := filtermatcher.NewMatcher(mp)
+	if err != nil {
+		return nil, err
+	}
+
+	var bodyFS filterset.FilterSet
+	if len(mp.LogBodies) > 0 {
+		bodyFS, err = filterset.CreateFilterSet(mp.LogBodies, &mp.Config)
+		if err != nil {
+			return nil, fmt.Errorf("error creating log record body filters: %w", err)
+		}
+	}
+
+	var severityTextFS filterset.FilterSet
+	if len(mp.LogSeverityTexts) > 0 {
+		severityTextFS, err = filterset.CreateFilterSet(mp.LogSeverityTexts, &mp.Config)
+		if err != nil {
+			return nil, fmt.Errorf("error creating log record severity text filters: %w", err)
+		}
+	}
+
+	return &propertiesMatcher{
+		PropertiesMatcher:   rm,
+		bodyFilters:         bodyFS,
+		severityTextFilters: severityTextFS,
+	}, nil
+}
+
+// SkipLogRecord determines if a log record should be processed.
+// True is returned when a log record should be skipped.
+// False is returned when a log record should not be skipped.
+// The logic determining if a log record should be processed is set
+// in the attribute configuration with the include and exclude settings.
+// Include properties are checked before exclude settings are checked.
+func SkipLogRecord(include Matcher, exclude Matcher, lr plog.LogRecord, resource pcommon.Resource, library pcommon.InstrumentationScope) bool {
+	if include != nil {
+		// A false returned in this case means the log record should not be processed.
+		if i := include.MatchLogRecord(lr, resource, library); !i {
+			return true
+		}
+	}
+
+	if exclude != nil {
+		// A true returned in this case means the log record should not be processed.
+		if e := exclude.MatchLogRecord(lr, resource, library); e {
+			return true
+		}
+	}
+
+	return false
+}
+
+// MatchLogRecord matches a log record to a set of properties.
+// The log record bodies and severity texts are matched, if specified.
+// The attributes, resources and libraries are then checked, if specified.
+// All specified properties must evaluate to true for a match to occur.
+func (mp *propertiesMatcher) MatchLogRecord(lr plog.LogRecord, resource pcommon.Resource, library pcommon.InstrumentationScope) bool {
+	// If a set of properties was not in the mp, all log records are considered to match on that property
+	if mp.bodyFilters != nil && !mp.bodyFilters.Matches(lr.Body().AsString()) {
+		return false
+	}
+
+	if mp.severityTextFilters != nil && !mp.severityTextFilters.Matches(lr.SeverityText()) {
+		return false
+	}
+
+	return mp.PropertiesMatcher.Match(lr.Attributes(), resource, library)
+}
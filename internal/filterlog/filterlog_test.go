@@ -0,0 +1,126 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filterlog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+
+	"github.com/mindtastic/opentelemetry-transparency-processor/internal/filterconfig"
+	"github.com/mindtastic/opentelemetry-transparency-processor/internal/filterset"
+)
+
+func createConfig(matchType filterset.MatchType) *filterset.Config {
+	return &filterset.Config{
+		MatchType: matchType,
+	}
+}
+
+func TestLogRecord_validateMatchesConfiguration_InvalidConfig(t *testing.T) {
+	testcases := []struct {
+		name        string
+		property    filterconfig.MatchProperties
+		errorString string
+	}{
+		{
+			name:        "empty_property",
+			property:    filterconfig.MatchProperties{},
+			errorString: `at least one of "attributes", "libraries", "resources", "log_bodies" or "log_severity_texts" field must be specified`,
+		},
+		{
+			name: "span_properties",
+			property: filterconfig.MatchProperties{
+				SpanNames: []string{"span"},
+			},
+			errorString: "neither services nor span_names should be specified for log records",
+		},
+		{
+			name: "invalid_match_type",
+			property: filterconfig.MatchProperties{
+				Config:    *createConfig("wrong_match_type"),
+				LogBodies: []string{"abc"},
+			},
+			errorString: "error creating log record body filters: unrecognized match_type: 'wrong_match_type', valid types are: [regexp strict]",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			output, err := NewMatcher(&tc.property)
+			assert.Nil(t, output)
+			require.EqualError(t, err, tc.errorString)
+		})
+	}
+}
+
+func TestLogRecord_Matching(t *testing.T) {
+	lr := plog.NewLogRecord()
+	lr.Body().SetStringVal("GET /users/1")
+	lr.SetSeverityText("INFO")
+	lr.Attributes().InsertString("http.host", "users")
+	resource := pcommon.NewResource()
+	library := pcommon.NewInstrumentationScope()
+
+	testcases := []struct {
+		name     string
+		property *filterconfig.MatchProperties
+		want     bool
+	}{
+		{
+			name: "body",
+			property: &filterconfig.MatchProperties{
+				Config:    *createConfig(filterset.Regexp),
+				LogBodies: []string{"^GET"},
+			},
+			want: true,
+		},
+		{
+			name: "body_mismatch",
+			property: &filterconfig.MatchProperties{
+				Config:    *createConfig(filterset.Regexp),
+				LogBodies: []string{"^POST"},
+			},
+		},
+		{
+			name: "severity_and_attributes",
+			property: &filterconfig.MatchProperties{
+				Config:           *createConfig(filterset.Strict),
+				LogSeverityTexts: []string{"INFO"},
+				Attributes:       []filterconfig.Attribute{{Key: "http.host", Value: "users"}},
+			},
+			want: true,
+		},
+		{
+			name: "severity_mismatch",
+			property: &filterconfig.MatchProperties{
+				Config:           *createConfig(filterset.Strict),
+				LogSeverityTexts: []string{"ERROR"},
+				Attributes:       []filterconfig.Attribute{{Key: "http.host", Value: "users"}},
+			},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			matcher, err := NewMatcher(tc.property)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, matcher.MatchLogRecord(lr, resource, library))
+			assert.Equal(t, !tc.want, SkipLogRecord(matcher, nil, lr, resource, library))
+			assert.Equal(t, tc.want, SkipLogRecord(nil, matcher, lr, resource, library))
+		})
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtermetric // import "github.com/open-telemetry/opentelemetry-collector-contrib/internal/coreinternal/processor/filtermetric"
+
+import (
+	"fmt"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+
+	"github.com/mindtastic/opentelemetry-transparency-processor/internal/filterconfig"
+	"github.com/mindtastic/opentelemetry-transparency-processor/internal/filtermatcher"
+	"github.com/mindtastic/opentelemetry-transparency-processor/internal/filterset"
+)
+
+// Matcher is an interface that allows matching a metric against a
+// configuration of a match.
+type Matcher interface {
+	MatchMetric(metric pmetric.Metric, resource pcommon.Resource, library pcommon.InstrumentationScope) bool
+}
+
+// propertiesMatcher allows matching a metric against various metric properties.
+type propertiesMatcher struct {
+	filtermatcher.PropertiesMatcher
+
+	// metric names to compare to.
+	nameFilters filterset.FilterSet
+}
+
+// NewMatcher creates a metric Matcher that matches based on the given MatchProperties.
+func NewMatcher(mp *filterconfig.MatchProperties) (Matcher, error) {
+	if mp == nil {
+		return nil, nil
+	}
+
+	if err := mp.ValidateForMetrics(); err != nil {
+		return nil, err
+	}
+
+	rm, err := filtermatcher.NewMatcher(mp)
+	if err != nil {
+		return nil, err
+	}
+
+	var nameFS filterset.FilterSet
+	if len(mp.MetricNames) > 0 {
+		nameFS, err = filterset.CreateFilterSet(mp.MetricNames, &mp.Config)
+		if err != nil {
+			return nil, fmt.Errorf("error creating metric name filters: %w", err)
+		}
+	}
+
+	return &propertiesMatcher{
+		PropertiesMatcher: rm,
+		nameFilters:       nameFS,
+	}, nil
+}
+
+// SkipMetric determines if a metric should be processed.
+// True is returned when a metric should be skipped.
+// False is returned when a metric should not be skipped.
+// The logic determining if a metric should be processed is set
+// in the attribute configuration with the include and exclude settings.
+// Include properties are checked before exclude settings are checked.
+func SkipMetric(include Matcher, exclude Matcher, metric pmetric.Metric, resource pcommon.Resource, library pcommon.InstrumentationScope) bool {
+	if include != nil {
+		// A false returned in this case means the metric should not be processed.
+		if i := include.MatchMetric(metric, resource, library); !i {
+			return true
+		}
+	}
+
+	if exclude != nil {
+		// A true returned in this case means the metric should not be processed.
+		if e := exclude.MatchMetric(metric, resource, library); e {
+			return true
+		}
+	}
+
+	return false
+}
+
+// MatchMetric matches a metric to a set of properties.
+// The metric names are matched, if specified.
+// The resources and libraries are then checked, if specified.
+// All specified properties must evaluate to true for a match to occur.
+func (mp *propertiesMatcher) MatchMetric(metric pmetric.Metric, resource pcommon.Resource, library pcommon.InstrumentationScope) bool {
+	// If a set of properties was not in the mp, all metrics are considered to match on that property
+	if mp.nameFilters != nil && !mp.nameFilters.Matches(metric.Name()) {
+		return false
+	}
+
+	// Metrics have no attributes of their own, they are kept on the data points.
+	return mp.PropertiesMatcher.Match(pcommon.NewMap(), resource, library)
+}
@@ -0,0 +1,139 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtermetric
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+
+	"github.com/mindtastic/opentelemetry-transparency-processor/internal/filterconfig"
+	"github.com/mindtastic/opentelemetry-transparency-processor/internal/filterset"
+)
+
+func createConfig(matchType filterset.MatchType) *filterset.Config {
+	return &filterset.Config{
+		MatchType: matchType,
+	}
+}
+
+func TestMetric_validateMatchesConfiguration_InvalidConfig(t *testing.T) {
+	testcases := []struct {
+		name        string
+		property    filterconfig.MatchProperties
+		errorString string
+	}{
+		{
+			name:        "empty_property",
+			property:    filterconfig.MatchProperties{},
+			errorString: `at least one of "metric_names", "libraries" or "resources" field must be specified`,
+		},
+		{
+			name: "span_properties",
+			property: filterconfig.MatchProperties{
+				Services: []string{"svc"},
+			},
+			errorString: "neither services nor span_names should be specified for metrics",
+		},
+		{
+			name: "log_properties",
+			property: filterconfig.MatchProperties{
+				LogBodies: []string{"body"},
+			},
+			errorString: "neither log_bodies nor log_severity_texts should be specified for metrics",
+		},
+		{
+			name: "attributes",
+			property: filterconfig.MatchProperties{
+				Attributes: []filterconfig.Attribute{{Key: "key"}},
+			},
+			errorString: "attributes should not be specified for metrics, use resources instead",
+		},
+		{
+			name: "invalid_match_type",
+			property: filterconfig.MatchProperties{
+				Config:      *createConfig("wrong_match_type"),
+				MetricNames: []string{"abc"},
+			},
+			errorString: "error creating metric name filters: unrecognized match_type: 'wrong_match_type', valid types are: [regexp strict]",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			output, err := NewMatcher(&tc.property)
+			assert.Nil(t, output)
+			require.EqualError(t, err, tc.errorString)
+		})
+	}
+}
+
+func TestMetric_Matching(t *testing.T) {
+	metric := pmetric.NewMetric()
+	metric.SetName("response_total")
+	resource := pcommon.NewResource()
+	resource.Attributes().InsertString("service.name", "users")
+	library := pcommon.NewInstrumentationScope()
+
+	testcases := []struct {
+		name     string
+		property *filterconfig.MatchProperties
+		want     bool
+	}{
+		{
+			name: "metric_name",
+			property: &filterconfig.MatchProperties{
+				Config:      *createConfig(filterset.Regexp),
+				MetricNames: []string{"^response_"},
+			},
+			want: true,
+		},
+		{
+			name: "metric_name_mismatch",
+			property: &filterconfig.MatchProperties{
+				Config:      *createConfig(filterset.Strict),
+				MetricNames: []string{"request_total"},
+			},
+		},
+		{
+			name: "metric_name_and_resource",
+			property: &filterconfig.MatchProperties{
+				Config:      *createConfig(filterset.Strict),
+				MetricNames: []string{"response_total"},
+				Resources:   []filterconfig.Attribute{{Key: "service.name", Value: "users"}},
+			},
+			want: true,
+		},
+		{
+			name: "resource_mismatch",
+			property: &filterconfig.MatchProperties{
+				Config:      *createConfig(filterset.Strict),
+				MetricNames: []string{"response_total"},
+				Resources:   []filterconfig.Attribute{{Key: "service.name", Value: "orders"}},
+			},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			matcher, err := NewMatcher(tc.property)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, matcher.MatchMetric(metric, resource, library))
+			assert.Equal(t, !tc.want, SkipMetric(matcher, nil, metric, resource, library))
+			assert.Equal(t, tc.want, SkipMetric(nil, matcher, metric, resource, library))
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package transparencyprocessor
+
+import (
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+const (
+	meshProfileLinkerd = "linkerd"
+	meshProfileIstio   = "istio"
+	meshProfileNone    = "none"
+
+	// attrIstioNodeID is the Envoy node ID, e.g. "sidecar~10.0.0.1~users-5d4f.shop~shop.svc.cluster.local".
+	attrIstioNodeID = "node_id"
+)
+
+// meshProfiles holds the defaults of the built-in profiles.
+var meshProfiles = map[string]MeshConfig{
+	meshProfileLinkerd: {
+		WorkloadAttributes: []string{
+			"linkerd.io/proxy-deployment",
+			"linkerd.io/proxy-statefulset",
+			"linkerd.io/proxy-daemonset",
+		},
+		ServiceNameFormat: "%s-proxy",
+	},
+	meshProfileIstio: {
+		WorkloadAttributes: []string{"istio.canonical_service", attrIstioNodeID},
+		ServiceNameFormat:  "%s-proxy",
+	},
+	meshProfileNone: {},
+}
+
+// mesh identifies the spans of one service mesh.
+type mesh struct {
+	profile           string
+	attributes        []string
+	serviceNameFormat string
+}
+
+func newMeshes(cfgs []MeshConfig) []mesh {
+	meshes := make([]mesh, 0, len(cfgs))
+	for _, cfg := range cfgs {
+		m := mesh{
+			profile:           cfg.Profile,
+			attributes:        meshProfiles[cfg.Profile].WorkloadAttributes,
+			serviceNameFormat: meshProfiles[cfg.Profile].ServiceNameFormat,
+		}
+		if len(cfg.WorkloadAttributes) > 0 {
+			m.attributes = cfg.WorkloadAttributes
+		}
+		if cfg.ServiceNameFormat != "" {
+			m.serviceNameFormat = cfg.ServiceNameFormat
+		}
+		meshes = append(meshes, m)
+	}
+	return meshes
+}
+
+// identify returns the service name of the proxy that emitted spans of
+// resource. It returns an empty name if service.name is to be kept, and false
+// if the resource does not belong to the mesh.
+func (m mesh) identify(resource pcommon.Resource) (string, bool) {
+	if m.profile == meshProfileNone {
+		return "", true
+	}
+	for _, key := range m.attributes {
+		v, ok := resource.Attributes().Get(key)
+		if !ok {
+			continue
+		}
+		workload := v.AsString()
+		if key == attrIstioNodeID {
+			workload = istioWorkload(workload)
+		}
+		if workload == "" {
+			continue
+		}
+		if m.serviceNameFormat == "" {
+			return "", true
+		}
+		return fmt.Sprintf(m.serviceNameFormat, workload), true
+	}
+	return "", false
+}
+
+// identifyProxy tries the configured meshes in order, see mesh.identify.
+func identifyProxy(meshes []mesh, resource pcommon.Resource) (string, bool) {
+	for _, m := range meshes {
+		if name, ok := m.identify(resource); ok {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// istioWorkload returns the pod name of an Envoy node ID of the form
+// "<type>~<ip>~<pod>.<namespace>~<domain>".
+func istioWorkload(nodeID string) string {
+	parts := strings.Split(nodeID, "~")
+	if len(parts) != 4 {
+		return ""
+	}
+	pod, _, _ := strings.Cut(parts[2], ".")
+	return pod
+}
@@ -0,0 +1,86 @@
+package transparencyprocessor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+func TestIdentifyProxy(t *testing.T) {
+	testCases := []struct {
+		name      string
+		meshes    []MeshConfig
+		resource  map[string]interface{}
+		want      string
+		wantFound bool
+	}{
+		{
+			name:      "linkerd deployment",
+			meshes:    []MeshConfig{{Profile: meshProfileLinkerd}},
+			resource:  map[string]interface{}{"linkerd.io/proxy-deployment": "users"},
+			want:      "users-proxy",
+			wantFound: true,
+		},
+		{
+			name:      "linkerd statefulset",
+			meshes:    []MeshConfig{{Profile: meshProfileLinkerd}},
+			resource:  map[string]interface{}{"linkerd.io/proxy-statefulset": "db"},
+			want:      "db-proxy",
+			wantFound: true,
+		},
+		{
+			name:     "linkerd without labels",
+			meshes:   []MeshConfig{{Profile: meshProfileLinkerd}},
+			resource: map[string]interface{}{"service.name": "users"},
+		},
+		{
+			name:      "istio canonical service",
+			meshes:    []MeshConfig{{Profile: meshProfileLinkerd}, {Profile: meshProfileIstio}},
+			resource:  map[string]interface{}{"istio.canonical_service": "users"},
+			want:      "users-proxy",
+			wantFound: true,
+		},
+		{
+			name:      "istio node id",
+			meshes:    []MeshConfig{{Profile: meshProfileIstio}},
+			resource:  map[string]interface{}{"node_id": "sidecar~10.0.0.1~users-5d4f.shop~shop.svc.cluster.local"},
+			want:      "users-5d4f-proxy",
+			wantFound: true,
+		},
+		{
+			name:     "istio malformed node id",
+			meshes:   []MeshConfig{{Profile: meshProfileIstio}},
+			resource: map[string]interface{}{"node_id": "users"},
+		},
+		{
+			name:      "no mesh keeps service name",
+			meshes:    []MeshConfig{{Profile: meshProfileLinkerd}, {Profile: meshProfileNone}},
+			resource:  map[string]interface{}{"service.name": "users"},
+			wantFound: true,
+		},
+		{
+			name:      "overrides",
+			meshes:    []MeshConfig{{Profile: meshProfileLinkerd, WorkloadAttributes: []string{"k8s.deployment.name"}, ServiceNameFormat: "mesh/%s"}},
+			resource:  map[string]interface{}{"k8s.deployment.name": "users"},
+			want:      "mesh/users",
+			wantFound: true,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			resource := pcommon.NewResource()
+			pcommon.NewMapFromRaw(tt.resource).CopyTo(resource.Attributes())
+			got, found := identifyProxy(newMeshes(tt.meshes), resource)
+			assert.Equal(t, tt.wantFound, found)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestMeshConfigValidate(t *testing.T) {
+	assert.NoError(t, (&MeshConfig{Profile: meshProfileIstio}).Validate())
+	assert.Error(t, (&MeshConfig{Profile: "consul"}).Validate())
+	assert.Error(t, (&MeshConfig{Profile: meshProfileLinkerd, ServiceNameFormat: "proxy"}).Validate())
+}
@@ -0,0 +1,148 @@
+package transparencyprocessor
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	"go.opentelemetry.io/collector/config/configtelemetry"
+	"go.opentelemetry.io/collector/obsreport"
+
+	"github.com/mindtastic/opentelemetry-transparency-processor/tilt"
+)
+
+var (
+	tagProcessorKey = tag.MustNewKey("processor")
+	tagReasonKey    = tag.MustNewKey("reason")
+	tagHostKey      = tag.MustNewKey("host")
+	tagPolicyKey    = tag.MustNewKey("policy")
+	tagCountryKey   = tag.MustNewKey("country")
+
+	statInvalidDocuments = stats.Int64("invalid_documents", "Number of fetched TILT documents that violate the TILT schema", stats.UnitDimensionless)
+	statSpansEnriched    = stats.Int64("spans_enriched", "Number of spans enriched with TILT attributes", stats.UnitDimensionless)
+	statSpansSkipped     = stats.Int64("spans_skipped", "Number of spans skipped by the include and exclude properties", stats.UnitDimensionless)
+	statCacheHits        = stats.Int64("cache_hits", "Number of span lookups answered by the attributes cache", stats.UnitDimensionless)
+	statCacheMisses      = stats.Int64("cache_misses", "Number of span lookups not answered by the attributes cache", stats.UnitDimensionless)
+	statCacheSize        = stats.Int64("cache_size", "Number of entries in the attributes cache", stats.UnitDimensionless)
+	statFetchLatency     = stats.Float64("fetch_latency", "Duration of fetching and validating a TILT document", stats.UnitMilliseconds)
+	statFetchErrors      = stats.Int64("fetch_errors", "Number of failed fetches of TILT documents", stats.UnitDimensionless)
+	statPolicyViolations = stats.Int64("policy_violations", "Number of spans violating a compliance policy", stats.UnitDimensionless)
+
+	statSpansRedacted       = stats.Int64("spans_redacted", "Number of spans with attributes redacted because of their TILT categories", stats.UnitDimensionless)
+	statUndeclaredTransfers = stats.Int64("undeclared_transfers", "Number of spans to a third country not declared in the TILT document", stats.UnitDimensionless)
+	statUnparsedLegalBases  = stats.Int64("unparsed_legal_bases", "Number of distinct legal basis references in fetched TILT documents that could not be normalized", stats.UnitDimensionless)
+)
+
+const (
+	fetchErrorNotFound = "not_found"
+	fetchErrorInvalid  = "invalid_document"
+	fetchErrorTimeout  = "timeout"
+	fetchErrorOther    = "error"
+)
+
+// metricViews returns the metrics views related to the transparency processor.
+func metricViews() []*view.View {
+	processorTagKeys := []tag.Key{tagProcessorKey}
+
+	sum := func(m stats.Measure, tagKeys []tag.Key) *view.View {
+		return &view.View{
+			Name:        obsreport.BuildProcessorCustomMetricName(typeStr, m.Name()),
+			Measure:     m,
+			Description: m.Description(),
+			TagKeys:     tagKeys,
+			Aggregation: view.Sum(),
+		}
+	}
+
+	return []*view.View{
+		sum(statInvalidDocuments, processorTagKeys),
+		sum(statSpansEnriched, processorTagKeys),
+		sum(statSpansSkipped, processorTagKeys),
+		sum(statSpansRedacted, processorTagKeys),
+		sum(statCacheHits, processorTagKeys),
+		sum(statCacheMisses, processorTagKeys),
+		sum(statUnparsedLegalBases, processorTagKeys),
+		sum(statFetchErrors, []tag.Key{tagProcessorKey, tagReasonKey, tagHostKey}),
+		sum(statPolicyViolations, []tag.Key{tagProcessorKey, tagPolicyKey}),
+		sum(statUndeclaredTransfers, []tag.Key{tagProcessorKey, tagCountryKey}),
+		{
+			Name:        obsreport.BuildProcessorCustomMetricName(typeStr, statCacheSize.Name()),
+			Measure:     statCacheSize,
+			Description: statCacheSize.Description(),
+			TagKeys:     processorTagKeys,
+			Aggregation: view.LastValue(),
+		},
+		{
+			Name:        obsreport.BuildProcessorCustomMetricName(typeStr, statFetchLatency.Name()),
+			Measure:     statFetchLatency,
+			Description: statFetchLatency.Description(),
+			TagKeys:     processorTagKeys,
+			Aggregation: view.Distribution(1, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000),
+		},
+	}
+}
+
+// record records the measurements if the telemetry level is at least level.
+// Spans enriched, skipped and redacted, fetch errors, policy violations, undeclared
+// transfers, invalid documents and unparsed legal bases are recorded
+// from the basic level, cache and latency metrics from the normal level. Fetch
+// errors are only tagged with the host at the detailed level.
+func (a *transparencyProcessor) record(level configtelemetry.Level, ms ...stats.Measurement) {
+	if a.telemetryLevel < level {
+		return
+	}
+	_ = stats.RecordWithTags(context.Background(), a.tags, ms...)
+}
+
+// recordFetchError counts a failed fetch for host.
+func (a *transparencyProcessor) recordFetchError(host string, err error) {
+	if a.telemetryLevel < configtelemetry.LevelBasic {
+		return
+	}
+	mutators := append([]tag.Mutator{tag.Upsert(tagReasonKey, fetchErrorReason(err))}, a.tags...)
+	if a.telemetryLevel >= configtelemetry.LevelDetailed {
+		mutators = append(mutators, tag.Upsert(tagHostKey, host))
+	}
+	_ = stats.RecordWithTags(context.Background(), mutators, statFetchErrors.M(1))
+}
+
+// recordViolations counts the spans violating each policy.
+func (a *transparencyProcessor) recordViolations(violations map[string]int64) {
+	if a.telemetryLevel < configtelemetry.LevelBasic {
+		return
+	}
+	for name, n := range violations {
+		mutators := append([]tag.Mutator{tag.Upsert(tagPolicyKey, name)}, a.tags...)
+		_ = stats.RecordWithTags(context.Background(), mutators, statPolicyViolations.M(n))
+	}
+}
+
+// recordTransfers counts the spans with undeclared transfers to each country.
+func (a *transparencyProcessor) recordTransfers(transfers map[string]int64) {
+	if a.telemetryLevel < configtelemetry.LevelBasic {
+		return
+	}
+	for country, n := range transfers {
+		mutators := append([]tag.Mutator{tag.Upsert(tagCountryKey, country)}, a.tags...)
+		_ = stats.RecordWithTags(context.Background(), mutators, statUndeclaredTransfers.M(n))
+	}
+}
+
+// fetchErrorReason classifies errors of updateAttributes.
+func fetchErrorReason(err error) string {
+	var ve *tilt.ValidationError
+	var netErr net.Error
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return fetchErrorNotFound
+	case errors.As(err, &ve):
+		return fetchErrorInvalid
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return fetchErrorTimeout
+	default:
+		return fetchErrorOther
+	}
+}
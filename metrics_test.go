@@ -0,0 +1,198 @@
+package transparencyprocessor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/config/configtelemetry"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/obsreport"
+
+	"github.com/mindtastic/opentelemetry-transparency-processor/internal/filterconfig"
+	"github.com/mindtastic/opentelemetry-transparency-processor/internal/filterset"
+	"github.com/mindtastic/opentelemetry-transparency-processor/tilt"
+)
+
+// resetViews re-registers the views to drop what other tests recorded.
+func resetViews(t *testing.T) {
+	view.Unregister(metricViews()...)
+	require.NoError(t, view.Register(metricViews()...))
+}
+
+// viewRows returns the rows recorded for the view of stat, keyed by their
+// tags other than the processor.
+func viewRows(t *testing.T, stat string) map[string]view.AggregationData {
+	rows, err := view.RetrieveData(obsreport.BuildProcessorCustomMetricName(typeStr, stat))
+	require.NoError(t, err)
+	data := make(map[string]view.AggregationData)
+	for _, r := range rows {
+		var key []string
+		for _, tg := range r.Tags {
+			if tg.Key != tagProcessorKey {
+				key = append(key, fmt.Sprintf("%s=%s", tg.Key.Name(), tg.Value))
+			}
+		}
+		data[fmt.Sprint(key)] = r.Data
+	}
+	return data
+}
+
+func sumValue(t *testing.T, stat string) float64 {
+	rows := viewRows(t, stat)
+	if len(rows) == 0 {
+		return 0
+	}
+	require.Len(t, rows, 1)
+	return rows["[]"].(*view.SumData).Value
+}
+
+func TestProcessTracesMetrics(t *testing.T) {
+	resetViews(t)
+	srv := newTiltServer(t, testTiltDocument)
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.ServiceMap = map[string]string{"testHost": srv.Listener.Addr().String()}
+	cfg.Exclude = &filterconfig.MatchProperties{
+		Config:    *createConfig(filterset.Strict),
+		SpanNames: []string{"/excluded"},
+	}
+	set := componenttest.NewNopProcessorCreateSettings()
+	set.MetricsLevel = configtelemetry.LevelNormal
+	tp, err := factory.CreateTracesProcessor(context.Background(), set, cfg, consumertest.NewNop())
+	require.NoError(t, err)
+	tp = startedProcessor(t, tp)
+
+	resourceAttrs := map[string]interface{}{"linkerd.io/proxy-deployment": "linkerd"}
+	attrs := map[string]interface{}{"http.host": "testHost"}
+	consume := func(path string) {
+		require.NoError(t, tp.ConsumeTraces(context.Background(), generateTraceData("linkerd-proxy", path, resourceAttrs, attrs)))
+	}
+
+	consume("/path")
+	assert.Eventually(t, func() bool { return len(viewRows(t, statFetchLatency.Name())) == 1 }, time.Second, 10*time.Millisecond)
+	consume("/path")
+	consume("/excluded")
+
+	assert.Equal(t, float64(1), sumValue(t, statSpansEnriched.Name()))
+	assert.Equal(t, float64(1), sumValue(t, statSpansSkipped.Name()))
+	assert.Equal(t, float64(1), sumValue(t, statCacheHits.Name()))
+	assert.Equal(t, float64(1), sumValue(t, statCacheMisses.Name()))
+	assert.Equal(t, float64(1), viewRows(t, statCacheSize.Name())["[]"].(*view.LastValueData).Value)
+	assert.Equal(t, int64(1), viewRows(t, statFetchLatency.Name())["[]"].(*view.DistributionData).Count)
+}
+
+func TestRecordFetchError(t *testing.T) {
+	tests := []struct {
+		level configtelemetry.Level
+		want  map[string]float64
+	}{
+		{level: configtelemetry.LevelNone, want: map[string]float64{}},
+		{level: configtelemetry.LevelBasic, want: map[string]float64{
+			"[reason=not_found]":        2,
+			"[reason=invalid_document]": 1,
+			"[reason=timeout]":          1,
+		}},
+		{level: configtelemetry.LevelDetailed, want: map[string]float64{
+			"[host=a reason=not_found]":        1,
+			"[host=b reason=not_found]":        1,
+			"[host=a reason=invalid_document]": 1,
+			"[host=b reason=timeout]":          1,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.level.String(), func(t *testing.T) {
+			resetViews(t)
+			tp := &transparencyProcessor{
+				telemetryLevel: tt.level,
+				tags:           []tag.Mutator{tag.Upsert(tagProcessorKey, "transparency")},
+			}
+			tp.recordFetchError("a", fmt.Errorf("%w for %q", ErrNotFound, "a"))
+			tp.recordFetchError("b", ErrNotFound)
+			tp.recordFetchError("a", fmt.Errorf("rejected: %w", &tilt.ValidationError{}))
+			tp.recordFetchError("b", fmt.Errorf("request failed: %w", context.DeadlineExceeded))
+
+			got := make(map[string]float64)
+			for k, v := range viewRows(t, statFetchErrors.Name()) {
+				got[k] = v.(*view.SumData).Value
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestFetchErrorReason(t *testing.T) {
+	assert.Equal(t, fetchErrorOther, fetchErrorReason(errors.New("connection refused")))
+}
+
+func TestUpdateAttributesLegalBases(t *testing.T) {
+	resetViews(t)
+	cfg := NewFactory().CreateDefaultConfig().(*Config)
+	cfg.Sources = []SourceConfig{{Type: sourceTypeStatic, Document: map[string]interface{}{
+		"dataDisclosed": []interface{}{
+			map[string]interface{}{"category": "email", "legalBases": []interface{}{
+				map[string]interface{}{"reference": "Art. 6(1)(a) GDPR"},
+				map[string]interface{}{"reference": "consent"},
+			}},
+			map[string]interface{}{"category": "employees", "legalBases": []interface{}{
+				map[string]interface{}{"reference": "§ 26 Abs. 1 BDSG"},
+			}},
+		},
+	}}}
+	set := componenttest.NewNopProcessorCreateSettings()
+	set.MetricsLevel = configtelemetry.LevelBasic
+	tp, err := newTransparencyProcessor(set, cfg)
+	require.NoError(t, err)
+
+	attr, err := tp.updateAttributes(context.Background(), "host", "/path")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Art. 6(1)(a) GDPR", "consent", "§ 26 Abs. 1 BDSG"}, attr.legalBases)
+	assert.Equal(t, []string{"GDPR-6-1-a", "", "BDSG-26-1"}, attr.canonicalLegalBases, "aligned with the legal bases")
+	assert.Equal(t, []string{"GDPR-6-1-a", ""}, attr.dataDisclosed[0].canonicalLegalBases)
+	assert.Equal(t, []string{"consent"}, attr.unparsedLegalBases)
+	assert.Equal(t, float64(1), sumValue(t, statUnparsedLegalBases.Name()))
+
+	_, err = tp.updateAttributes(context.Background(), "host", "/path")
+	require.NoError(t, err)
+	_, err = tp.updateAttributes(context.Background(), "other", "/")
+	require.NoError(t, err)
+	assert.Equal(t, float64(1), sumValue(t, statUnparsedLegalBases.Name()), "references are counted once")
+}
+
+func TestProcessTracesNegativeEntries(t *testing.T) {
+	resetViews(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	t.Cleanup(srv.Close)
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.ServiceMap = map[string]string{"testHost": srv.Listener.Addr().String()}
+	set := componenttest.NewNopProcessorCreateSettings()
+	set.MetricsLevel = configtelemetry.LevelNormal
+	tp, err := factory.CreateTracesProcessor(context.Background(), set, cfg, consumertest.NewNop())
+	require.NoError(t, err)
+	tp = startedProcessor(t, tp)
+
+	resourceAttrs := map[string]interface{}{"linkerd.io/proxy-deployment": "linkerd"}
+	attrs := map[string]interface{}{"http.host": "testHost"}
+	require.NoError(t, tp.ConsumeTraces(context.Background(), generateTraceData("linkerd-proxy", "/path", resourceAttrs, attrs)))
+	assert.Eventually(t, func() bool { return len(viewRows(t, statFetchErrors.Name())) == 1 }, time.Second, 10*time.Millisecond)
+
+	td := generateTraceData("linkerd-proxy", "/path", resourceAttrs, attrs)
+	require.NoError(t, tp.ConsumeTraces(context.Background(), td))
+	sortAttributes(td)
+	assert.Equal(t, generateTraceData("linkerd-proxy", "/path", resourceAttrs, attrs), td, "negative entries are not added")
+	assert.Equal(t, float64(0), sumValue(t, statSpansEnriched.Name()))
+	assert.Equal(t, float64(0), sumValue(t, statCacheHits.Name()))
+	assert.Equal(t, float64(2), sumValue(t, statCacheMisses.Name()))
+}
@@ -0,0 +1,116 @@
+package transparencyprocessor
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mindtastic/opentelemetry-transparency-processor/tilt"
+)
+
+// attrViolations lists the names of the policies an enriched span violates.
+const attrViolations = "tilt.violations"
+
+// policy checks the TILT attributes of a span against a PolicyConfig.
+type policy struct {
+	name                    string
+	categories              []string
+	automatedDecisionMaking bool
+	purposes                []string
+	legalBases              []string
+}
+
+func newPolicies(cfgs []PolicyConfig) []policy {
+	policies := make([]policy, 0, len(cfgs))
+	for _, cfg := range cfgs {
+		p := policy{
+			name:                    cfg.Name,
+			automatedDecisionMaking: cfg.When.AutomatedDecisionMaking,
+			purposes:                cfg.Require.Purposes,
+		}
+		for _, l := range cfg.Require.LegalBases {
+			if canonical, ok := tilt.NormalizeLegalBasis(l); ok {
+				l = canonical
+			}
+			p.legalBases = append(p.legalBases, l)
+		}
+		p.categories = cfg.When.Categories
+		policies = append(policies, p)
+	}
+	return policies
+}
+
+// violated reports whether attr violates the policy. With categories, the
+// requirements are checked against the declarations of every matching
+// category, otherwise against those of the whole document.
+func (p policy) violated(attr tiltAttributes) bool {
+	if p.automatedDecisionMaking && !attr.automatedDecision {
+		return false
+	}
+	if len(p.categories) == 0 {
+		return !p.satisfied(attr.puproses, concat(attr.legalBases, attr.canonicalLegalBases))
+	}
+	for _, d := range attr.dataDisclosed {
+		if containsAny(p.categories, []string{d.category}, strings.EqualFold) && !p.satisfied(d.purposes, concat(d.legalBases, d.canonicalLegalBases)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p policy) satisfied(purposes, legalBases []string) bool {
+	if len(p.purposes) > 0 && !containsAny(purposes, p.purposes, strings.EqualFold) {
+		return false
+	}
+	if len(p.legalBases) > 0 && !containsAny(legalBases, p.legalBases, legalBasisMatches) {
+		return false
+	}
+	return true
+}
+
+func concat(a, b []string) []string {
+	return append(append(make([]string, 0, len(a)+len(b)), a...), b...)
+}
+
+func containsAny(values, wanted []string, match func(value, want string) bool) bool {
+	for _, v := range values {
+		for _, w := range wanted {
+			if match(v, w) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// legalBasisMatches reports whether reference is want or a subdivision of it,
+// e.g. GDPR-22-2-a matches GDPR-22, but GDPR-2 does not match GDPR-22.
+func legalBasisMatches(reference, want string) bool {
+	reference, want = strings.ToUpper(reference), strings.ToUpper(want)
+	return reference == want || strings.HasPrefix(reference, want+"-")
+}
+
+// evaluatePolicies returns the names of the policies attr violates.
+func evaluatePolicies(policies []policy, attr tiltAttributes) []string {
+	var violations []string
+	for _, p := range policies {
+		if p.violated(attr) {
+			violations = append(violations, p.name)
+		}
+	}
+	return violations
+}
+
+// Validate checks if the policy configuration is valid.
+func (cfg *PolicyConfig) Validate() error {
+	if cfg.Name == "" {
+		return errors.New("name must be specified")
+	}
+	if len(cfg.When.Categories) == 0 && !cfg.When.AutomatedDecisionMaking {
+		return fmt.Errorf("policy %q: when must specify categories or automated_decision_making", cfg.Name)
+	}
+	if len(cfg.Require.Purposes) == 0 && len(cfg.Require.LegalBases) == 0 {
+		return fmt.Errorf("policy %q: require must specify purposes or legal_bases", cfg.Name)
+	}
+	return nil
+}
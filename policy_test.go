@@ -0,0 +1,146 @@
+package transparencyprocessor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opencensus.io/stats/view"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/config/configtelemetry"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+)
+
+func TestEvaluatePolicies(t *testing.T) {
+	policies := newPolicies([]PolicyConfig{
+		{
+			Name:    "health-for-therapy",
+			When:    PolicyCondition{Categories: []string{"health"}},
+			Require: PolicyRequirement{Purposes: []string{"therapy"}},
+		},
+		{
+			Name:    "automated-decisions",
+			When:    PolicyCondition{AutomatedDecisionMaking: true},
+			Require: PolicyRequirement{LegalBases: []string{"Art. 22 GDPR"}},
+		},
+	})
+
+	testCases := []struct {
+		name string
+		attr tiltAttributes
+		want []string
+	}{
+		{
+			name: "other categories",
+			attr: tiltAttributes{dataDisclosed: []disclosedAttributes{{category: "email"}}},
+		},
+		{
+			name: "declared purpose",
+			attr: tiltAttributes{dataDisclosed: []disclosedAttributes{
+				{category: "email", purposes: []string{"newsletter"}},
+				{category: "health", purposes: []string{"Therapy"}},
+			}},
+		},
+		{
+			name: "undeclared purpose",
+			attr: tiltAttributes{dataDisclosed: []disclosedAttributes{
+				{category: "email", purposes: []string{"therapy"}},
+				{category: "health", purposes: []string{"marketing"}},
+			}},
+			want: []string{"health-for-therapy"},
+		},
+		{
+			name: "category matched ignoring case",
+			attr: tiltAttributes{dataDisclosed: []disclosedAttributes{
+				{category: "Health", purposes: []string{"marketing"}},
+			}},
+			want: []string{"health-for-therapy"},
+		},
+		{
+			name: "category only containing the policy category",
+			attr: tiltAttributes{dataDisclosed: []disclosedAttributes{
+				{category: "Health data", purposes: []string{"marketing"}},
+			}},
+		},
+		{
+			name: "automated decision with legal basis",
+			attr: tiltAttributes{automatedDecision: true, legalBases: []string{"GDPR-22-2-a"}},
+		},
+		{
+			name: "automated decision with canonical legal basis",
+			attr: tiltAttributes{automatedDecision: true, legalBases: []string{"Art. 22(2)(a) GDPR"}, canonicalLegalBases: []string{"GDPR-22-2-a"}},
+		},
+		{
+			name: "automated decision without legal basis",
+			attr: tiltAttributes{automatedDecision: true, legalBases: []string{"GDPR-2-1", "GDPR-6-1-a"}},
+			want: []string{"automated-decisions"},
+		},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, evaluatePolicies(policies, tt.attr))
+		})
+	}
+}
+
+func TestPolicyConfigValidate(t *testing.T) {
+	cfg := NewFactory().CreateDefaultConfig().(*Config)
+	valid := PolicyConfig{
+		Name:    "health",
+		When:    PolicyCondition{Categories: []string{"health"}},
+		Require: PolicyRequirement{Purposes: []string{"therapy"}},
+	}
+	cfg.Policies = []PolicyConfig{valid}
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Policies = []PolicyConfig{valid, valid}
+	assert.Error(t, cfg.Validate(), "duplicate names")
+
+	assert.Error(t, (&PolicyConfig{When: valid.When, Require: valid.Require}).Validate())
+	assert.Error(t, (&PolicyConfig{Name: "a", Require: valid.Require}).Validate())
+	assert.Error(t, (&PolicyConfig{Name: "a", When: valid.When}).Validate())
+}
+
+func TestProcessTracesPolicies(t *testing.T) {
+	resetViews(t)
+	srv := newTiltServer(t, testTiltDocument)
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.ServiceMap = map[string]string{"testHost": srv.Listener.Addr().String()}
+	cfg.Policies = []PolicyConfig{
+		{
+			Name:    "testing-for-research",
+			When:    PolicyCondition{Categories: []string{"testing"}},
+			Require: PolicyRequirement{Purposes: []string{"research"}},
+		},
+		{
+			Name:    "testing-with-consent",
+			When:    PolicyCondition{Categories: []string{"testing"}},
+			Require: PolicyRequirement{LegalBases: []string{"GDPR-6-1-a"}},
+		},
+	}
+	set := componenttest.NewNopProcessorCreateSettings()
+	set.MetricsLevel = configtelemetry.LevelBasic
+	tp, err := factory.CreateTracesProcessor(context.Background(), set, cfg, consumertest.NewNop())
+	require.NoError(t, err)
+	tp = startedProcessor(t, tp)
+
+	runIndividualTestCase(t, testCase{
+		name:               "policies",
+		serviceName:        "linkerd-proxy",
+		resourceAttributes: map[string]interface{}{"linkerd.io/proxy-deployment": "linkerd"},
+		inputAttributes:    map[string]interface{}{"http.host": "testHost"},
+		expectedAttributes: testEnrichedAttributes(map[string]interface{}{
+			"http.host":       "testHost",
+			"tilt.violations": []interface{}{"testing-for-research"},
+		}),
+	}, tp)
+
+	assert.Eventually(t, func() bool {
+		rows := viewRows(t, statPolicyViolations.Name())
+		d, ok := rows["[policy=testing-for-research]"]
+		return ok && len(rows) == 1 && d.(*view.SumData).Value >= 1
+	}, time.Second, 10*time.Millisecond)
+}
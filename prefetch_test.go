@@ -0,0 +1,80 @@
+package transparencyprocessor
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+)
+
+func TestPrefetch(t *testing.T) {
+	release := make(chan struct{})
+	var mu sync.Mutex
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		mu.Unlock()
+		_, _ = w.Write([]byte(testTiltDocument))
+	}))
+	defer srv.Close()
+
+	cfg := NewFactory().CreateDefaultConfig().(*Config)
+	cfg.ServiceMap = map[string]string{"testHost": srv.Listener.Addr().String()}
+	cfg.Prefetch = PrefetchConfig{Enabled: true, Paths: []string{"/users"}}
+	require.NoError(t, cfg.Validate())
+	tp, err := newTransparencyProcessor(componenttest.NewNopProcessorCreateSettings(), cfg)
+	require.NoError(t, err)
+	require.NoError(t, tp.start(context.Background(), componenttest.NewNopHost()))
+	defer func() { require.NoError(t, tp.shutdown(context.Background())) }()
+
+	rec := httptest.NewRecorder()
+	tp.handleReady(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	close(release)
+	assert.Eventually(t, tp.isReady, time.Second, 10*time.Millisecond)
+	rec = httptest.NewRecorder()
+	tp.handleReady(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	assert.ElementsMatch(t, []string{"/tilt", "/tilt/users"}, paths)
+	for _, k := range []string{"testHost", "testHost/users"} {
+		_, ok, _ := tp.attributesCache.get(k)
+		assert.True(t, ok, k)
+	}
+	// The path has no document of its own yet, the root document is used.
+	td := generateTraceData("linkerd-proxy", "/orders/1", map[string]interface{}{"linkerd.io/proxy-deployment": "linkerd"}, map[string]interface{}{"http.host": "testHost"})
+	td, err = tp.processTraces(context.Background(), td)
+	require.NoError(t, err)
+	categories, ok := td.ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0).Attributes().Get(attrCategories)
+	require.True(t, ok, "first batch is enriched")
+	assert.Equal(t, "testing", categories.SliceVal().At(0).StringVal())
+}
+
+func TestReadyEndpoint(t *testing.T) {
+	ln, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	endpoint := ln.Addr().String()
+	require.NoError(t, ln.Close())
+
+	cfg := NewFactory().CreateDefaultConfig().(*Config)
+	cfg.Server.Endpoint = endpoint
+	tp, err := newTransparencyProcessor(componenttest.NewNopProcessorCreateSettings(), cfg)
+	require.NoError(t, err)
+	require.NoError(t, tp.start(context.Background(), componenttest.NewNopHost()))
+	defer func() { require.NoError(t, tp.shutdown(context.Background())) }()
+
+	resp, err := http.Get("http://" + endpoint + "/ready")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "ready without prefetch")
+}
@@ -0,0 +1,189 @@
+package transparencyprocessor
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	conventions "go.opentelemetry.io/collector/semconv/v1.6.1"
+)
+
+// redactionMask replaces masked values.
+const redactionMask = "****"
+
+// specialCategories are matched if a redaction rule lists no categories. They
+// name the special categories of personal data of Art. 9(1) GDPR.
+var specialCategories = []string{
+	"racial", "ethnic", "political", "religious", "philosophical", "trade union",
+	"genetic", "biometric", "health", "sex life", "sexual orientation",
+}
+
+// Attributes of the stable HTTP semantic conventions, which the semconv
+// version in use does not define yet.
+const (
+	attrURLFull  = "url.full"
+	attrURLQuery = "url.query"
+)
+
+// queryAttributes hold URLs or targets whose query strings are redacted.
+// url.query holds the query string alone, see redactParams.
+var queryAttributes = []string{conventions.AttributeHTTPURL, conventions.AttributeHTTPTarget, attrURLFull}
+
+// redaction scrubs span attributes if a TILT document declares one of its
+// categories, or if no document is known and the rule fails closed.
+type redaction struct {
+	categories   []string
+	action       RedactionAction
+	attributes   []string
+	queryStrings bool
+	failClosed   bool
+	hashKey      []byte
+}
+
+func newRedactions(cfgs []RedactionConfig) []redaction {
+	redactions := make([]redaction, 0, len(cfgs))
+	for _, cfg := range cfgs {
+		r := redaction{
+			categories:   specialCategories,
+			action:       cfg.Action,
+			attributes:   cfg.Attributes,
+			queryStrings: cfg.QueryStrings,
+			failClosed:   cfg.FailClosed,
+			hashKey:      []byte(cfg.HashKey),
+		}
+		if len(cfg.Categories) > 0 {
+			r.categories = make([]string, 0, len(cfg.Categories))
+			for _, c := range cfg.Categories {
+				r.categories = append(r.categories, strings.ToLower(c))
+			}
+		}
+		redactions = append(redactions, r)
+	}
+	return redactions
+}
+
+// matches reports whether attr declares a category that contains one of the
+// categories of r, ignoring case. Without a resolved document it only matches
+// if r fails closed.
+func (r redaction) matches(attr tiltAttributes) bool {
+	if !attr.resolved {
+		return r.failClosed
+	}
+	for _, category := range attr.categories {
+		category = strings.ToLower(category)
+		for _, c := range r.categories {
+			if strings.Contains(category, c) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// apply redacts the attributes and query strings of r in attrs.
+func (r redaction) apply(attrs pcommon.Map) {
+	for _, k := range r.attributes {
+		v, ok := attrs.Get(k)
+		if !ok {
+			continue
+		}
+		if r.action == RedactionDelete {
+			attrs.Remove(k)
+			continue
+		}
+		attrs.UpdateString(k, r.redact(v.AsString()))
+	}
+	if !r.queryStrings {
+		return
+	}
+	for _, k := range queryAttributes {
+		if v, ok := attrs.Get(k); ok {
+			attrs.UpdateString(k, r.redactQuery(v.AsString()))
+		}
+	}
+	if v, ok := attrs.Get(attrURLQuery); ok {
+		if r.action == RedactionDelete {
+			attrs.Remove(attrURLQuery)
+		} else {
+			attrs.UpdateString(attrURLQuery, r.redactParams(v.AsString()))
+		}
+	}
+}
+
+// redact returns the hashed or masked value.
+func (r redaction) redact(value string) string {
+	if r.action == RedactionHash {
+		mac := hmac.New(sha256.New, r.hashKey)
+		mac.Write([]byte(value))
+		return hex.EncodeToString(mac.Sum(nil))
+	}
+	return redactionMask
+}
+
+// redactQuery removes the query string of target or redacts its parameters
+// with redactParams, keeping the fragment.
+func (r redaction) redactQuery(target string) string {
+	base, query, ok := strings.Cut(target, "?")
+	if !ok {
+		return target
+	}
+	query, fragment, hasFragment := strings.Cut(query, "#")
+	if hasFragment {
+		fragment = "#" + fragment
+	}
+	if r.action == RedactionDelete || query == "" {
+		return base + fragment
+	}
+	return base + "?" + r.redactParams(query) + fragment
+}
+
+// redactParams redacts the value of each parameter of query, keeping their
+// names. Parameters without a value, as in "?alice@example.com", are redacted
+// as a whole.
+func (r redaction) redactParams(query string) string {
+	params := strings.Split(query, "&")
+	for i, p := range params {
+		if p == "" {
+			continue
+		}
+		if name, value, ok := strings.Cut(p, "="); ok {
+			params[i] = name + "=" + r.redact(value)
+		} else {
+			params[i] = r.redact(p)
+		}
+	}
+	return strings.Join(params, "&")
+}
+
+// redactSpan applies every redaction whose categories attr declares to attrs
+// and reports whether any did.
+func redactSpan(redactions []redaction, attr tiltAttributes, attrs pcommon.Map) bool {
+	redacted := false
+	for _, r := range redactions {
+		if r.matches(attr) {
+			r.apply(attrs)
+			redacted = true
+		}
+	}
+	return redacted
+}
+
+// Validate checks if the redaction configuration is valid.
+func (cfg *RedactionConfig) Validate() error {
+	switch cfg.Action {
+	case RedactionDelete, RedactionHash, RedactionMask:
+	default:
+		return fmt.Errorf("unknown action %q, valid actions are: %v", cfg.Action, []RedactionAction{RedactionDelete, RedactionHash, RedactionMask})
+	}
+	if cfg.Action == RedactionHash && cfg.HashKey == "" {
+		return errors.New("hash_key must be specified for action hash")
+	}
+	if len(cfg.Attributes) == 0 && !cfg.QueryStrings {
+		return errors.New("attributes or query_strings must be specified")
+	}
+	return nil
+}
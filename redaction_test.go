@@ -0,0 +1,200 @@
+package transparencyprocessor
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/config/configtelemetry"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+func TestRedactionApply(t *testing.T) {
+	input := map[string]interface{}{
+		"enduser.id":  "alice",
+		"http.url":    "https://therapy/sessions?user=alice&note=sad#top",
+		"http.target": "/sessions?user=alice&flag",
+		"url.full":    "https://therapy/sessions?alice@example.com",
+		"url.query":   "user=alice&flag",
+		"http.host":   "therapy",
+	}
+	tests := []struct {
+		action RedactionAction
+		want   map[string]interface{}
+	}{
+		{action: RedactionDelete, want: map[string]interface{}{
+			"http.url":    "https://therapy/sessions#top",
+			"http.target": "/sessions",
+			"url.full":    "https://therapy/sessions",
+			"http.host":   "therapy",
+		}},
+		{action: RedactionMask, want: map[string]interface{}{
+			"enduser.id":  "****",
+			"http.url":    "https://therapy/sessions?user=****&note=****#top",
+			"http.target": "/sessions?user=****&****",
+			"url.full":    "https://therapy/sessions?****",
+			"url.query":   "user=****&****",
+			"http.host":   "therapy",
+		}},
+		{action: RedactionHash, want: map[string]interface{}{
+			"enduser.id":  "4360c67bc81025114044578d7c4e8e0f02fd0cae99f22d603390e8f9dc9888f8",
+			"http.url":    "https://therapy/sessions?user=4360c67bc81025114044578d7c4e8e0f02fd0cae99f22d603390e8f9dc9888f8&note=48c49f04123780be476938984863e0c7b518b8362bb25e87677b3c95ebd9e7b4#top",
+			"http.target": "/sessions?user=4360c67bc81025114044578d7c4e8e0f02fd0cae99f22d603390e8f9dc9888f8&192d03f91dac370dad1b293037dc5e79fc157d765aa27b6c1376c13b5918db9d",
+			"url.full":    "https://therapy/sessions?a398d49ce1980b3642bc4dbd110121e3c953e1eadb497d50dea23e9611f83ee7",
+			"url.query":   "user=4360c67bc81025114044578d7c4e8e0f02fd0cae99f22d603390e8f9dc9888f8&192d03f91dac370dad1b293037dc5e79fc157d765aa27b6c1376c13b5918db9d",
+			"http.host":   "therapy",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.action), func(t *testing.T) {
+			r := newRedactions([]RedactionConfig{{Action: tt.action, HashKey: "secret", Attributes: []string{"enduser.id", "session.id"}, QueryStrings: true}})[0]
+			attrs := pcommon.NewMapFromRaw(input)
+			r.apply(attrs)
+			assert.Equal(t, pcommon.NewMapFromRaw(tt.want).Sort(), attrs.Sort())
+		})
+	}
+}
+
+func TestRedactSpan(t *testing.T) {
+	redactions := newRedactions([]RedactionConfig{
+		{Action: RedactionDelete, Attributes: []string{"enduser.id"}},
+		{Categories: []string{"Location"}, Action: RedactionMask, Attributes: []string{"geo"}},
+	})
+	input := map[string]interface{}{"enduser.id": "alice", "geo": "52.5,13.4"}
+
+	tests := []struct {
+		name       string
+		categories []string
+		want       map[string]interface{}
+	}{
+		{name: "other category", categories: []string{"email"}, want: input},
+		{name: "special category", categories: []string{"email", "Health Data"}, want: map[string]interface{}{"geo": "52.5,13.4"}},
+		{name: "configured category", categories: []string{"location"}, want: map[string]interface{}{"enduser.id": "alice", "geo": "****"}},
+		{name: "both", categories: []string{"biometric", "precise location"}, want: map[string]interface{}{"geo": "****"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attrs := pcommon.NewMapFromRaw(input)
+			redacted := redactSpan(redactions, tiltAttributes{categories: tt.categories, resolved: true}, attrs)
+			assert.Equal(t, tt.name != "other category", redacted)
+			assert.Equal(t, pcommon.NewMapFromRaw(tt.want).Sort(), attrs.Sort())
+		})
+	}
+}
+
+func TestRedactSpanUnresolved(t *testing.T) {
+	redactions := newRedactions([]RedactionConfig{
+		{Action: RedactionDelete, Attributes: []string{"enduser.id"}, FailClosed: true},
+		{Action: RedactionMask, Attributes: []string{"geo"}},
+	})
+	attrs := pcommon.NewMapFromRaw(map[string]interface{}{"enduser.id": "alice", "geo": "52.5,13.4"})
+	assert.True(t, redactSpan(redactions, tiltAttributes{}, attrs))
+	assert.Equal(t, pcommon.NewMapFromRaw(map[string]interface{}{"geo": "52.5,13.4"}).Sort(), attrs.Sort(), "only rules that fail closed apply")
+
+	attrs = pcommon.NewMapFromRaw(map[string]interface{}{"enduser.id": "alice"})
+	assert.False(t, redactSpan(redactions, tiltAttributes{categories: []string{"email"}, resolved: true}, attrs), "resolved documents are matched by category")
+	assert.Equal(t, pcommon.NewMapFromRaw(map[string]interface{}{"enduser.id": "alice"}), attrs)
+}
+
+func TestRedactionConfigValidate(t *testing.T) {
+	cfg := NewFactory().CreateDefaultConfig().(*Config)
+	cfg.Redaction = []RedactionConfig{{Action: RedactionHash, HashKey: "secret", Attributes: []string{"enduser.id"}}}
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Redaction = []RedactionConfig{{Action: RedactionHash, Attributes: []string{"enduser.id"}}}
+	assert.Error(t, cfg.Validate(), "hash requires a key")
+
+	cfg.Redaction = []RedactionConfig{{Action: "encrypt", Attributes: []string{"enduser.id"}}}
+	assert.Error(t, cfg.Validate())
+
+	cfg.Redaction = []RedactionConfig{{Action: RedactionMask}}
+	assert.Error(t, cfg.Validate())
+}
+
+func TestProcessTracesRedaction(t *testing.T) {
+	resetViews(t)
+	srv := newTiltServer(t, testTiltDocument)
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.ServiceMap = map[string]string{"testHost": srv.Listener.Addr().String()}
+	cfg.Redaction = []RedactionConfig{{Categories: []string{"testing"}, Action: RedactionDelete, Attributes: []string{"enduser.id"}, QueryStrings: true}}
+	set := componenttest.NewNopProcessorCreateSettings()
+	set.MetricsLevel = configtelemetry.LevelBasic
+	tp, err := factory.CreateTracesProcessor(context.Background(), set, cfg, consumertest.NewNop())
+	require.NoError(t, err)
+	tp = startedProcessor(t, tp)
+
+	runIndividualTestCase(t, testCase{
+		name:               "redaction",
+		serviceName:        "linkerd-proxy",
+		resourceAttributes: map[string]interface{}{"linkerd.io/proxy-deployment": "linkerd"},
+		inputAttributes: map[string]interface{}{
+			"http.host":   "testHost",
+			"http.target": "/sessions?user=alice",
+			"enduser.id":  "alice",
+		},
+		expectedAttributes: testEnrichedAttributes(map[string]interface{}{
+			"http.host":   "testHost",
+			"http.target": "/sessions",
+		}),
+	}, tp)
+
+	assert.Eventually(t, func() bool { return sumValue(t, statSpansRedacted.Name()) >= 1 }, time.Second, 10*time.Millisecond)
+}
+
+func TestProcessTracesRedactionFailClosed(t *testing.T) {
+	resetViews(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	t.Cleanup(srv.Close)
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.ServiceMap = map[string]string{"testHost": srv.Listener.Addr().String()}
+	cfg.Redaction = []RedactionConfig{
+		{Action: RedactionMask, Attributes: []string{"enduser.id"}, FailClosed: true},
+		{Action: RedactionDelete, Attributes: []string{"session.id"}},
+	}
+	require.NoError(t, cfg.Validate())
+	set := componenttest.NewNopProcessorCreateSettings()
+	set.MetricsLevel = configtelemetry.LevelBasic
+	tp, err := factory.CreateTracesProcessor(context.Background(), set, cfg, consumertest.NewNop())
+	require.NoError(t, err)
+	tp = startedProcessor(t, tp)
+
+	resourceAttrs := map[string]interface{}{"linkerd.io/proxy-deployment": "linkerd"}
+	attrs := map[string]interface{}{"http.host": "testHost", "enduser.id": "alice", "session.id": "42"}
+	want := map[string]interface{}{"http.host": "testHost", "enduser.id": "****", "session.id": "42"}
+
+	// Not cached yet.
+	td := generateTraceData("linkerd-proxy", "/path", resourceAttrs, attrs)
+	require.NoError(t, tp.ConsumeTraces(context.Background(), td))
+	sortAttributes(td)
+	assert.Equal(t, generateTraceData("linkerd-proxy", "/path", resourceAttrs, want), td)
+
+	// Failed to fetch.
+	assert.Eventually(t, func() bool { return len(viewRows(t, statFetchErrors.Name())) == 1 }, time.Second, 10*time.Millisecond)
+	td = generateTraceData("linkerd-proxy", "/path", resourceAttrs, attrs)
+	require.NoError(t, tp.ConsumeTraces(context.Background(), td))
+	sortAttributes(td)
+	assert.Equal(t, generateTraceData("linkerd-proxy", "/path", resourceAttrs, want), td)
+
+	// No host can be extracted.
+	td = generateTraceData("linkerd-proxy", "/path", resourceAttrs, map[string]interface{}{"enduser.id": "alice"})
+	require.NoError(t, tp.ConsumeTraces(context.Background(), td))
+	assert.Equal(t, generateTraceData("linkerd-proxy", "/path", resourceAttrs, map[string]interface{}{"enduser.id": "****"}), td)
+
+	// Not sent by a mesh proxy.
+	td = generateTraceData("users", "/path", nil, attrs)
+	require.NoError(t, tp.ConsumeTraces(context.Background(), td))
+	sortAttributes(td)
+	assert.Equal(t, generateTraceData("users", "/path", nil, want), td)
+
+	assert.Eventually(t, func() bool { return sumValue(t, statSpansRedacted.Name()) == 4 }, time.Second, 10*time.Millisecond)
+}
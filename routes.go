@@ -0,0 +1,136 @@
+package transparencyprocessor
+
+import (
+	"net"
+	"strings"
+	"sync"
+)
+
+// routeTemplate is a path like /users/{id}. Segments in braces match any
+// single non-empty path segment.
+type routeTemplate struct {
+	template  string
+	segments  []string
+	wildcards int
+}
+
+func newRouteTemplate(template string) routeTemplate {
+	t := routeTemplate{template: template, segments: splitPath(template)}
+	for _, s := range t.segments {
+		if isParameter(s) {
+			t.wildcards++
+		}
+	}
+	return t
+}
+
+func (t routeTemplate) match(segments []string) bool {
+	if len(segments) != len(t.segments) {
+		return false
+	}
+	for i, s := range t.segments {
+		if isParameter(s) {
+			if segments[i] == "" {
+				return false
+			}
+			continue
+		}
+		if s != segments[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func isParameter(segment string) bool {
+	return len(segment) > 2 && strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}")
+}
+
+func splitPath(p string) []string {
+	p = strings.Trim(p, "/")
+	if p == "" {
+		return nil
+	}
+	return strings.Split(p, "/")
+}
+
+// routes normalizes concrete paths to route templates, so that all requests
+// to /users/123 and /users/456 share the cache entry of /users/{id}. Templates
+// are configured per host or learned from the routes of fetched TILT
+// documents. Configured templates take precedence over learned ones.
+type routes struct {
+	configured map[string][]routeTemplate
+
+	mu      sync.RWMutex
+	learned map[string][]routeTemplate
+}
+
+func newRoutes(cfg map[string][]string) *routes {
+	r := &routes{
+		configured: make(map[string][]routeTemplate, len(cfg)),
+		learned:    make(map[string][]routeTemplate),
+	}
+	for host, templates := range cfg {
+		r.configured[host] = newRouteTemplates(templates)
+	}
+	return r
+}
+
+func newRouteTemplates(templates []string) []routeTemplate {
+	ts := make([]routeTemplate, 0, len(templates))
+	for _, t := range templates {
+		ts = append(ts, newRouteTemplate(t))
+	}
+	return ts
+}
+
+// learn replaces the templates learned for host.
+func (r *routes) learn(host string, templates []string) {
+	if len(templates) == 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.learned[host] = newRouteTemplates(templates)
+}
+
+// normalize returns the template of host that matches p, preferring the
+// template with the fewest parameters. Configured templates are tried before
+// the learned ones. If none matches, p is returned.
+func (r *routes) normalize(host, p string) string {
+	segments := splitPath(p)
+	if t, ok := bestMatch(lookupHost(r.configured, host), segments); ok {
+		return t
+	}
+	r.mu.RLock()
+	learned := lookupHost(r.learned, host)
+	r.mu.RUnlock()
+	if t, ok := bestMatch(learned, segments); ok {
+		return t
+	}
+	return p
+}
+
+func bestMatch(templates []routeTemplate, segments []string) (string, bool) {
+	var best *routeTemplate
+	for i, t := range templates {
+		if t.match(segments) && (best == nil || t.wildcards < best.wildcards) {
+			best = &templates[i]
+		}
+	}
+	if best == nil {
+		return "", false
+	}
+	return best.template, true
+}
+
+// lookupHost returns the templates of host, with or without port.
+func lookupHost(m map[string][]routeTemplate, host string) []routeTemplate {
+	if ts, ok := m[host]; ok {
+		return ts
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return m[h]
+	}
+	return nil
+}
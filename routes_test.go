@@ -0,0 +1,67 @@
+package transparencyprocessor
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+)
+
+func TestRoutesNormalize(t *testing.T) {
+	r := newRoutes(map[string][]string{
+		"users": {"/users/{id}", "/users/me", "/users/{id}/orders/{order}"},
+	})
+
+	assert.Equal(t, "/users/{id}", r.normalize("users", "/users/123"))
+	assert.Equal(t, "/users/{id}", r.normalize("users:8080", "/users/123/"), "port and trailing slash are ignored")
+	assert.Equal(t, "/users/me", r.normalize("users", "/users/me"), "literal segments are preferred")
+	assert.Equal(t, "/users/{id}/orders/{order}", r.normalize("users", "/users/1/orders/2"))
+	assert.Equal(t, "/users", r.normalize("users", "/users"))
+	assert.Equal(t, "/users/123", r.normalize("orders", "/users/123"))
+
+	r.learn("orders", []string{"/orders/{id}"})
+	r.learn("users", []string{"/{anything}/{id}"})
+	assert.Equal(t, "/orders/{id}", r.normalize("orders", "/orders/1"))
+	assert.Equal(t, "/users/{id}", r.normalize("users", "/users/123"), "configured templates take precedence")
+}
+
+func TestProcessTracesRoutes(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		_, _ = w.Write([]byte(`{"dataDisclosed": [{"category": "testing"}], "routes": ["/orders/{id}"]}`))
+	}))
+	defer srv.Close()
+
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.ServiceMap = map[string]string{"testHost": srv.Listener.Addr().String()}
+	cfg.Routes = map[string][]string{"testHost": {"/users/{id}"}}
+	tp, err := factory.CreateTracesProcessor(context.Background(), componenttest.NewNopProcessorCreateSettings(), cfg, consumertest.NewNop())
+	require.NoError(t, err)
+	tp = startedProcessor(t, tp)
+
+	resourceAttrs := map[string]interface{}{"linkerd.io/proxy-deployment": "linkerd"}
+	attrs := map[string]interface{}{"http.host": "testHost"}
+	enriched := func(path string) bool {
+		td := generateTraceData("linkerd-proxy", path, resourceAttrs, attrs)
+		require.NoError(t, tp.ConsumeTraces(context.Background(), td))
+		_, ok := td.ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0).Attributes().Get(attrCategories)
+		return ok
+	}
+
+	assert.Eventually(t, func() bool { return enriched("/users/1") }, time.Second, 10*time.Millisecond)
+	assert.True(t, enriched("/users/2"), "concrete paths share the template's entry")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+
+	assert.Eventually(t, func() bool { return enriched("/orders/1") }, time.Second, 10*time.Millisecond)
+	assert.True(t, enriched("/orders/2"), "templates are learned from the document")
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+}
@@ -0,0 +1,62 @@
+package transparencyprocessor
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config/confighttp"
+)
+
+// server serves the local HTTP endpoints of the processor, e.g. for health
+// checks. It is disabled if no endpoint is configured.
+type server struct {
+	settings  confighttp.HTTPServerSettings
+	telemetry component.TelemetrySettings
+	mux       *http.ServeMux
+	srv       *http.Server
+	wg        sync.WaitGroup
+}
+
+func newServer(settings confighttp.HTTPServerSettings, telemetry component.TelemetrySettings) *server {
+	return &server{settings: settings, telemetry: telemetry, mux: http.NewServeMux()}
+}
+
+// handle registers handler for pattern. It must be called before Start.
+func (s *server) handle(pattern string, handler http.HandlerFunc) {
+	s.mux.Handle(pattern, handler)
+}
+
+func (s *server) Start(_ context.Context, host component.Host) error {
+	if s.settings.Endpoint == "" {
+		return nil
+	}
+	ln, err := s.settings.ToListener()
+	if err != nil {
+		return err
+	}
+	s.srv, err = s.settings.ToServer(host, s.telemetry, s.mux)
+	if err != nil {
+		_ = ln.Close()
+		return err
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		if err := s.srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			host.ReportFatalError(err)
+		}
+	}()
+	return nil
+}
+
+func (s *server) Shutdown(ctx context.Context) error {
+	if s.srv == nil {
+		return nil
+	}
+	err := s.srv.Shutdown(ctx)
+	s.wg.Wait()
+	return err
+}
@@ -0,0 +1,146 @@
+package transparencyprocessor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/mindtastic/opentelemetry-transparency-processor/tilt"
+)
+
+const snapshotVersion = 1
+
+// snapshot is the on-disk format of the attributes cache. Entries keep the
+// TILT documents they were derived from, so that the attributes are rebuilt
+// with the current code when the snapshot is loaded.
+type snapshot struct {
+	Version int             `json:"version"`
+	Entries []snapshotEntry `json:"entries"`
+}
+
+type snapshotEntry struct {
+	Key              string          `json:"key"`
+	Host             string          `json:"host"`
+	Path             string          `json:"path"`
+	LastUpdated      time.Time       `json:"last_updated"`
+	ValidationErrors []string        `json:"validation_errors,omitempty"`
+	Document         json.RawMessage `json:"document"`
+}
+
+// marshalSnapshot encodes the entries of c that hold a document.
+func marshalSnapshot(c *attributesCache) ([]byte, error) {
+	s := snapshot{Version: snapshotVersion}
+	c.mu.RLock()
+	for k, e := range c.entries {
+		if len(e.attributes.document) == 0 {
+			continue
+		}
+		s.Entries = append(s.Entries, snapshotEntry{
+			Key:              k,
+			Host:             e.host,
+			Path:             e.path,
+			LastUpdated:      e.attributes.lastUpdated,
+			ValidationErrors: e.attributes.validationErrors,
+			Document:         e.attributes.document,
+		})
+	}
+	c.mu.RUnlock()
+
+	b, err := json.Marshal(s)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding cache snapshot: %w", err)
+	}
+	return b, nil
+}
+
+// writeSnapshot writes the entries of c that hold a document to file. The
+// file is replaced atomically, so a crash never leaves a partial snapshot.
+func writeSnapshot(file string, c *attributesCache) error {
+	b, err := marshalSnapshot(c)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".*")
+	if err != nil {
+		return fmt.Errorf("error writing cache snapshot: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(b); err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), file)
+	}
+	if err != nil {
+		return fmt.Errorf("error writing cache snapshot: %w", err)
+	}
+	return nil
+}
+
+// readSnapshot decodes the entries of file. A missing file is not an error.
+func readSnapshot(file string) ([]snapshotEntry, error) {
+	b, err := os.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading cache snapshot: %w", err)
+	}
+	var s snapshot
+	if err = json.Unmarshal(b, &s); err != nil {
+		return nil, fmt.Errorf("error decoding cache snapshot: %w", err)
+	}
+	if s.Version != snapshotVersion {
+		return nil, fmt.Errorf("unsupported cache snapshot version %d", s.Version)
+	}
+	return s.Entries, nil
+}
+
+// loadSnapshot loads the entries of the snapshot file into the cache and
+// learns the routes of their documents, and returns the number of entries
+// loaded. Entries whose document cannot be decoded are skipped. Entries keep
+// the time they were fetched, so that they are refreshed as if the collector
+// had not been restarted.
+func (a *transparencyProcessor) loadSnapshot() (int, error) {
+	entries, err := readSnapshot(a.snapshot.Path)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, e := range entries {
+		doc, err := tilt.Unmarshal(e.Document)
+		if err != nil {
+			a.logger.Warn("skipping cache snapshot entry", zap.String("key", e.Key), zap.Error(err))
+			continue
+		}
+		a.routes.learn(e.Host, doc.Routes)
+		attributes := newTiltAttributes(doc)
+		attributes.lastUpdated = e.LastUpdated
+		attributes.validationErrors = e.ValidationErrors
+		a.attributesCache.restore(e.Key, e.Host, e.Path, attributes)
+		n++
+	}
+	return n, nil
+}
+
+// handleCache serves the current cache in the snapshot format, e.g. for
+// reports on the live cache.
+func (a *transparencyProcessor) handleCache(w http.ResponseWriter, _ *http.Request) {
+	b, err := marshalSnapshot(a.attributesCache)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(b)
+}
@@ -0,0 +1,140 @@
+package transparencyprocessor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.uber.org/zap"
+
+	"github.com/mindtastic/opentelemetry-transparency-processor/tilt"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "cache.json")
+	c, clock := newTestCache(time.Minute)
+
+	doc, err := tilt.Unmarshal([]byte(testTiltDocument))
+	require.NoError(t, err)
+	attributes := newTiltAttributes(doc)
+	attributes.validationErrors = []string{"/meta: missing properties"}
+	c.set("host/a", "host", "/a", attributes)
+	clock.advance(30 * time.Second)
+	c.fail("host/b", "host", "/b")
+	require.NoError(t, writeSnapshot(file, c))
+
+	restored, restoredClock := newTestCache(time.Minute)
+	restoredClock.t = clock.t
+	n, err := newSnapshotProcessor(file, restored).loadSnapshot()
+	require.NoError(t, err)
+	assert.Equal(t, 1, n, "negative entries are not persisted")
+
+	attr, ok, stale := restored.get("host/a")
+	require.True(t, ok)
+	assert.False(t, stale)
+	assert.Equal(t, []string{"testing"}, attr.categories)
+	assert.Equal(t, attributes.validationErrors, attr.validationErrors)
+	assert.True(t, attr.lastUpdated.Equal(clock.t.Add(-30*time.Second)), "timestamps are kept")
+	host, path, _ := restored.source("host/a")
+	assert.Equal(t, "host", host)
+	assert.Equal(t, "/a", path)
+
+	restoredClock.advance(30 * time.Second)
+	_, _, stale = restored.get("host/a")
+	assert.True(t, stale, "ttl applies across restarts")
+}
+
+// newSnapshotProcessor returns a processor that loads the snapshot file into c.
+func newSnapshotProcessor(file string, c *attributesCache) *transparencyProcessor {
+	return &transparencyProcessor{
+		logger:          zap.NewNop(),
+		attributesCache: c,
+		routes:          newRoutes(nil),
+		snapshot:        SnapshotConfig{Path: file},
+	}
+}
+
+func TestReadSnapshotErrors(t *testing.T) {
+	dir := t.TempDir()
+
+	entries, err := readSnapshot(filepath.Join(dir, "missing.json"))
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+
+	writeFile(t, dir, "broken.json", `{"version": 1, "entries": [`)
+	_, err = readSnapshot(filepath.Join(dir, "broken.json"))
+	assert.Error(t, err)
+
+	writeFile(t, dir, "future.json", `{"version": 2, "entries": []}`)
+	_, err = readSnapshot(filepath.Join(dir, "future.json"))
+	assert.Error(t, err)
+}
+
+func TestLoadSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "cache.json", `{"version": 1, "entries": [
+		{"key": "bad", "host": "bad", "path": "", "document": "not a document"},
+		{"key": "users/users/{id}", "host": "users", "path": "/users/1", "last_updated": "2022-01-01T00:00:00Z",
+		 "document": {"routes": ["/users/{id}"], "dataDisclosed": [{"category": "email"}]}}
+	]}`)
+	c, _ := newTestCache(time.Minute)
+	tp := newSnapshotProcessor(filepath.Join(dir, "cache.json"), c)
+
+	n, err := tp.loadSnapshot()
+	require.NoError(t, err)
+	assert.Equal(t, 1, n, "broken entries are skipped")
+	assert.Equal(t, 1, c.len())
+
+	k := tp.cacheKey("users", "/users/123")
+	assert.Equal(t, "users/users/{id}", k, "routes of the documents are learned")
+	attr, ok, _ := c.get(k)
+	require.True(t, ok)
+	assert.Equal(t, []string{"email"}, attr.categories)
+}
+
+func TestProcessTracesSnapshot(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "cache.json")
+	srv := newTiltServer(t, testTiltDocument)
+	factory := NewFactory()
+	newConfig := func(address string) *Config {
+		cfg := factory.CreateDefaultConfig().(*Config)
+		cfg.ServiceMap = map[string]string{"testHost": address}
+		cfg.Cache.Snapshot.Path = file
+		require.NoError(t, cfg.Validate())
+		return cfg
+	}
+	tt := testCase{
+		name:               "snapshot",
+		serviceName:        "linkerd-proxy",
+		resourceAttributes: map[string]interface{}{"linkerd.io/proxy-deployment": "linkerd"},
+		inputAttributes:    map[string]interface{}{"http.host": "testHost"},
+		expectedAttributes: testEnrichedAttributes(map[string]interface{}{
+			"http.host": "testHost",
+		}),
+	}
+
+	tp, err := factory.CreateTracesProcessor(context.Background(), componenttest.NewNopProcessorCreateSettings(), newConfig(srv.Listener.Addr().String()), consumertest.NewNop())
+	require.NoError(t, err)
+	require.NoError(t, tp.Start(context.Background(), componenttest.NewNopHost()))
+	runIndividualTestCase(t, tt, tp)
+	require.NoError(t, tp.Shutdown(context.Background()))
+	_, err = os.Stat(file)
+	require.NoError(t, err, "snapshot is written on shutdown")
+
+	// After the restart, the service is unreachable.
+	srv.Close()
+	tp, err = factory.CreateTracesProcessor(context.Background(), componenttest.NewNopProcessorCreateSettings(), newConfig(srv.Listener.Addr().String()), consumertest.NewNop())
+	require.NoError(t, err)
+	tp = startedProcessor(t, tp)
+
+	td := generateTraceData(tt.serviceName, tt.name, tt.resourceAttributes, tt.inputAttributes)
+	require.NoError(t, tp.ConsumeTraces(context.Background(), td))
+	sortAttributes(td)
+	assert.Equal(t, generateTraceData(tt.serviceName, tt.name, tt.resourceAttributes, tt.expectedAttributes), td, "first batch is enriched from the snapshot")
+}
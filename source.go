@@ -0,0 +1,97 @@
+package transparencyprocessor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/mindtastic/opentelemetry-transparency-processor/tilt"
+	"go.opentelemetry.io/collector/component"
+	"go.uber.org/multierr"
+)
+
+const (
+	sourceTypeHTTP   = "http"
+	sourceTypeFiles  = "files"
+	sourceTypeStatic = "static"
+)
+
+// ErrNotFound is returned by a TiltSource that has no document for a host and
+// path, so that the next source is asked.
+var ErrNotFound = errors.New("no TILT document found")
+
+// TiltSource resolves the TILT document that applies to requests to host and path.
+// Sources that need to be started, e.g. to watch files or to set up clients,
+// additionally implement component.Component.
+type TiltSource interface {
+	Resolve(ctx context.Context, host, path string) (*tilt.Document, error)
+}
+
+// sourceChain asks its sources in order and returns the first document found.
+type sourceChain []TiltSource
+
+var _ TiltSource = (sourceChain)(nil)
+
+func newSourceChain(set component.ProcessorCreateSettings, cfg *Config, onChange func(hosts []string)) (sourceChain, error) {
+	chain := make(sourceChain, 0, len(cfg.Sources))
+	for i, sc := range cfg.Sources {
+		var s TiltSource
+		switch sc.Type {
+		case sourceTypeHTTP:
+			s = newHTTPSource(set.TelemetrySettings, cfg.Client, cfg.ServiceMap)
+		case sourceTypeFiles:
+			s = newFileSource(set.Logger, sc.FilesConfig, onChange)
+		case sourceTypeStatic:
+			ss, err := newStaticSource(sc.Hosts, sc.Document)
+			if err != nil {
+				return nil, fmt.Errorf("sources[%d]: %w", i, err)
+			}
+			s = ss
+		default:
+			return nil, fmt.Errorf("sources[%d]: unknown type %q", i, sc.Type)
+		}
+		chain = append(chain, s)
+	}
+	return chain, nil
+}
+
+// Resolve returns the document of the first source that has one. If no
+// source has a document, the errors of all failed sources are returned, or
+// ErrNotFound if none failed.
+func (c sourceChain) Resolve(ctx context.Context, host, path string) (*tilt.Document, error) {
+	var errs error
+	for _, s := range c {
+		spec, err := s.Resolve(ctx, host, path)
+		if err == nil {
+			return spec, nil
+		}
+		if !errors.Is(err, ErrNotFound) {
+			errs = multierr.Append(errs, err)
+		}
+	}
+	if errs != nil {
+		return nil, errs
+	}
+	return nil, fmt.Errorf("%w for %q", ErrNotFound, attributeKey(host, path))
+}
+
+func (c sourceChain) Start(ctx context.Context, host component.Host) error {
+	for _, s := range c {
+		if comp, ok := s.(component.Component); ok {
+			if err := comp.Start(ctx, host); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (c sourceChain) Shutdown(ctx context.Context) error {
+	var errs error
+	for _, s := range c {
+		if comp, ok := s.(component.Component); ok {
+			errs = multierr.Append(errs, comp.Shutdown(ctx))
+		}
+	}
+	return errs
+}
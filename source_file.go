@@ -0,0 +1,219 @@
+package transparencyprocessor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/mindtastic/opentelemetry-transparency-processor/tilt"
+	"go.opentelemetry.io/collector/component"
+	"go.uber.org/zap"
+)
+
+// fileSource serves TILT documents from a local directory. A document
+// applies to every path of its host. Hosts are mapped to files through an
+// optional manifest, otherwise a file named <host>.json is used.
+// The directory is watched and documents are reloaded whenever it changes.
+type fileSource struct {
+	logger    *zap.Logger
+	directory string
+	manifest  string
+
+	// onChange is called with the hosts whose document was added, changed or
+	// removed by a reload.
+	onChange func(hosts []string)
+
+	mu    sync.RWMutex
+	specs map[string]*tilt.Document
+	raw   map[string]string
+
+	watcher *fsnotify.Watcher
+	wg      sync.WaitGroup
+}
+
+func newFileSource(logger *zap.Logger, cfg FilesConfig, onChange func(hosts []string)) *fileSource {
+	return &fileSource{
+		logger:    logger,
+		directory: cfg.Directory,
+		manifest:  cfg.Manifest,
+		onChange:  onChange,
+		specs:     make(map[string]*tilt.Document),
+		raw:       make(map[string]string),
+	}
+}
+
+var _ TiltSource = (*fileSource)(nil)
+
+func (p *fileSource) Start(context.Context, component.Host) error {
+	if err := p.reload(); err != nil {
+		return err
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("error creating watcher: %w", err)
+	}
+	if err := watcher.Add(p.directory); err != nil {
+		watcher.Close()
+		return fmt.Errorf("error watching %q: %w", p.directory, err)
+	}
+	p.watcher = watcher
+	p.wg.Add(1)
+	go p.watch()
+	return nil
+}
+
+func (p *fileSource) Shutdown(context.Context) error {
+	if p.watcher == nil {
+		return nil
+	}
+	err := p.watcher.Close()
+	p.wg.Wait()
+	return err
+}
+
+// reloadDelay is how long the directory must be quiet before a change is
+// reloaded, so that the events of one update cause a single reload.
+const reloadDelay = 50 * time.Millisecond
+
+// watch reloads the documents on every change of the directory. Changes are
+// not filtered by name: Kubernetes updates mounted ConfigMaps by swapping a
+// ..data symlink, which does not touch the *.json links themselves.
+func (p *fileSource) watch() {
+	defer p.wg.Done()
+	timer := time.NewTimer(reloadDelay)
+	if !timer.Stop() {
+		<-timer.C
+	}
+	defer timer.Stop()
+	for {
+		select {
+		case ev, ok := <-p.watcher.Events:
+			if !ok {
+				return
+			}
+			if ev.Op == fsnotify.Chmod {
+				continue
+			}
+			timer.Reset(reloadDelay)
+		case <-timer.C:
+			if err := p.reload(); err != nil {
+				p.logger.Warn("error reloading TILT documents", zap.String("directory", p.directory), zap.Error(err))
+			}
+		case err, ok := <-p.watcher.Errors:
+			if !ok {
+				return
+			}
+			p.logger.Warn("error watching TILT documents", zap.String("directory", p.directory), zap.Error(err))
+		}
+	}
+}
+
+func (p *fileSource) Resolve(_ context.Context, host, _ string) (*tilt.Document, error) {
+	if s, ok := p.spec(host); ok {
+		return s, nil
+	}
+	return nil, ErrNotFound
+}
+
+// spec returns the document for host. A host with a port falls back to the
+// document of the bare host name.
+func (p *fileSource) spec(host string) (*tilt.Document, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if s, ok := p.specs[host]; ok {
+		return s, true
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		s, ok := p.specs[h]
+		return s, ok
+	}
+	return nil, false
+}
+
+// reload reads all documents from the directory. Documents that cannot be
+// read keep their previous version. The files are read without holding the
+// lock, so lookups are not blocked by the disk; reloads are not concurrent,
+// they only run on Start and in watch.
+func (p *fileSource) reload() error {
+	files, err := p.files()
+	if err != nil {
+		return err
+	}
+
+	specs := make(map[string]*tilt.Document, len(files))
+	raw := make(map[string]string, len(files))
+	var failed []string
+	for host, name := range files {
+		b, err := os.ReadFile(filepath.Join(p.directory, name))
+		if err == nil {
+			var spec *tilt.Document
+			if spec, err = tilt.Unmarshal(b); err == nil {
+				specs[host], raw[host] = spec, string(b)
+				continue
+			}
+		}
+		p.logger.Warn("error reading TILT document", zap.String("host", host), zap.String("file", name), zap.Error(err))
+		failed = append(failed, host)
+	}
+
+	p.mu.Lock()
+	for _, host := range failed {
+		if s, ok := p.specs[host]; ok {
+			specs[host], raw[host] = s, p.raw[host]
+		}
+	}
+
+	var changed []string
+	for host, b := range raw {
+		if old, ok := p.raw[host]; !ok || old != b {
+			changed = append(changed, host)
+		}
+	}
+	for host := range p.raw {
+		if _, ok := raw[host]; !ok {
+			changed = append(changed, host)
+		}
+	}
+	p.specs, p.raw = specs, raw
+	p.mu.Unlock()
+
+	if len(changed) > 0 && p.onChange != nil {
+		p.onChange(changed)
+	}
+	return nil
+}
+
+// files maps hosts to document file names, either from the manifest or from
+// the names of the JSON files in the directory.
+func (p *fileSource) files() (map[string]string, error) {
+	files := make(map[string]string)
+	if p.manifest != "" {
+		b, err := os.ReadFile(filepath.Join(p.directory, p.manifest))
+		if err != nil {
+			return nil, fmt.Errorf("error reading manifest: %w", err)
+		}
+		if err := json.Unmarshal(b, &files); err != nil {
+			return nil, fmt.Errorf("error decoding manifest: %w", err)
+		}
+		return files, nil
+	}
+
+	entries, err := os.ReadDir(p.directory)
+	if err != nil {
+		return nil, fmt.Errorf("error reading directory: %w", err)
+	}
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".json" {
+			continue
+		}
+		files[strings.TrimSuffix(e.Name(), ".json")] = e.Name()
+	}
+	return files, nil
+}
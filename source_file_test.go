@@ -0,0 +1,103 @@
+package transparencyprocessor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.uber.org/zap"
+)
+
+func writeFile(t *testing.T, dir, name, content string) {
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0600))
+}
+
+func TestFileSource(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "users.json", `{"dataDisclosed": [{"category": "email"}]}`)
+
+	var mu sync.Mutex
+	var changed []string
+	p := newFileSource(zap.NewNop(), FilesConfig{Directory: dir}, func(hosts []string) {
+		mu.Lock()
+		defer mu.Unlock()
+		changed = append(changed, hosts...)
+	})
+	require.NoError(t, p.Start(context.Background(), componenttest.NewNopHost()))
+	defer func() { assert.NoError(t, p.Shutdown(context.Background())) }()
+
+	spec, ok := p.spec("users")
+	require.True(t, ok)
+	assert.Equal(t, "email", spec.DataDisclosed[0].Category)
+	_, ok = p.spec("users:8080")
+	assert.True(t, ok, "port is ignored")
+	_, ok = p.spec("orders")
+	assert.False(t, ok)
+
+	writeFile(t, dir, "users.json", `{"dataDisclosed": [{"category": "phone"}]}`)
+	assert.Eventually(t, func() bool {
+		spec, ok := p.spec("users")
+		return ok && spec.DataDisclosed[0].Category == "phone"
+	}, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, os.Remove(filepath.Join(dir, "users.json")))
+	assert.Eventually(t, func() bool {
+		_, ok := p.spec("users")
+		return !ok
+	}, time.Second, 10*time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Contains(t, changed, "users")
+}
+
+func TestFileSourceManifest(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "manifest.json", `{"users.default.svc": "users-tilt.json"}`)
+	writeFile(t, dir, "users-tilt.json", `{"dataDisclosed": [{"category": "email"}]}`)
+
+	p := newFileSource(zap.NewNop(), FilesConfig{Directory: dir, Manifest: "manifest.json"}, nil)
+	require.NoError(t, p.Start(context.Background(), componenttest.NewNopHost()))
+	defer func() { assert.NoError(t, p.Shutdown(context.Background())) }()
+
+	_, ok := p.spec("users.default.svc")
+	assert.True(t, ok)
+	_, ok = p.spec("users-tilt")
+	assert.False(t, ok)
+}
+
+// TestFileSourceConfigMap updates the directory the way the kubelet updates a
+// mounted ConfigMap: the documents are links into ..data, which is swapped to
+// a new directory atomically.
+func TestFileSourceConfigMap(t *testing.T) {
+	dir := t.TempDir()
+	writeVersion := func(version, content string) {
+		require.NoError(t, os.Mkdir(filepath.Join(dir, version), 0700))
+		writeFile(t, filepath.Join(dir, version), "users.json", content)
+		require.NoError(t, os.Symlink(version, filepath.Join(dir, "..data_tmp")))
+		require.NoError(t, os.Rename(filepath.Join(dir, "..data_tmp"), filepath.Join(dir, "..data")))
+	}
+	writeVersion("..v1", `{"dataDisclosed": [{"category": "email"}]}`)
+	require.NoError(t, os.Symlink(filepath.Join("..data", "users.json"), filepath.Join(dir, "users.json")))
+
+	p := newFileSource(zap.NewNop(), FilesConfig{Directory: dir}, nil)
+	require.NoError(t, p.Start(context.Background(), componenttest.NewNopHost()))
+	defer func() { assert.NoError(t, p.Shutdown(context.Background())) }()
+
+	spec, ok := p.spec("users")
+	require.True(t, ok)
+	assert.Equal(t, "email", spec.DataDisclosed[0].Category)
+
+	writeVersion("..v2", `{"dataDisclosed": [{"category": "phone"}]}`)
+	require.NoError(t, os.RemoveAll(filepath.Join(dir, "..v1")))
+	assert.Eventually(t, func() bool {
+		spec, ok := p.spec("users")
+		return ok && spec.DataDisclosed[0].Category == "phone"
+	}, time.Second, 10*time.Millisecond)
+}
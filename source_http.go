@@ -0,0 +1,80 @@
+package transparencyprocessor
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/mindtastic/opentelemetry-transparency-processor/tilt"
+	"go.opentelemetry.io/collector/component"
+)
+
+// httpSource requests TILT documents from the /tilt endpoint of the services.
+type httpSource struct {
+	telemetry  component.TelemetrySettings
+	settings   ClientConfig
+	serviceMap map[string]string
+	client     *http.Client
+}
+
+var _ TiltSource = (*httpSource)(nil)
+
+func newHTTPSource(telemetry component.TelemetrySettings, settings ClientConfig, serviceMap map[string]string) *httpSource {
+	return &httpSource{
+		telemetry:  telemetry,
+		settings:   settings,
+		serviceMap: serviceMap,
+	}
+}
+
+func (s *httpSource) Start(_ context.Context, host component.Host) error {
+	client, err := s.settings.ToClient(host.GetExtensions(), s.telemetry)
+	if err != nil {
+		return fmt.Errorf("error creating TILT client: %w", err)
+	}
+	s.client = client
+	return nil
+}
+
+func (s *httpSource) Shutdown(context.Context) error {
+	return nil
+}
+
+func (s *httpSource) Resolve(ctx context.Context, httpHost, httpPath string) (*tilt.Document, error) {
+	host, ok := s.serviceMap[httpHost]
+	if !ok {
+		host = httpHost
+	}
+	if !strings.Contains(httpPath, "tilt/") {
+		httpPath = "tilt/" + httpPath
+	}
+	u := url.URL{
+		Scheme: s.settings.Scheme,
+		Host:   host,
+		Path:   path.Clean(httpPath),
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request for %q: %v", u.String(), err)
+	}
+	res, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching spec from %q: %v", u.String(), err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w at %q", ErrNotFound, u.String())
+	}
+	if res.StatusCode >= 400 {
+		return nil, fmt.Errorf("error fetching spec from %q: %s", u.String(), res.Status)
+	}
+	doc, err := tilt.Decode(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding spec from %q: %v", u.String(), err)
+	}
+	return doc, nil
+}
@@ -0,0 +1,53 @@
+package transparencyprocessor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+
+	"github.com/mindtastic/opentelemetry-transparency-processor/tilt"
+)
+
+// staticSource serves a TILT document from the configuration, either for a
+// fixed set of hosts or, without hosts, as a default for all of them.
+type staticSource struct {
+	hosts map[string]struct{}
+	spec  *tilt.Document
+}
+
+var _ TiltSource = (*staticSource)(nil)
+
+func newStaticSource(hosts []string, document map[string]interface{}) (*staticSource, error) {
+	b, err := json.Marshal(document)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding static document: %w", err)
+	}
+	spec, err := tilt.Unmarshal(b)
+	if err != nil {
+		return nil, err
+	}
+	s := &staticSource{spec: spec}
+	if len(hosts) > 0 {
+		s.hosts = make(map[string]struct{}, len(hosts))
+		for _, h := range hosts {
+			s.hosts[h] = struct{}{}
+		}
+	}
+	return s, nil
+}
+
+func (s *staticSource) Resolve(_ context.Context, host, _ string) (*tilt.Document, error) {
+	if s.hosts == nil {
+		return s.spec, nil
+	}
+	if _, ok := s.hosts[host]; ok {
+		return s.spec, nil
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		if _, ok := s.hosts[h]; ok {
+			return s.spec, nil
+		}
+	}
+	return nil, ErrNotFound
+}
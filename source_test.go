@@ -0,0 +1,75 @@
+package transparencyprocessor
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+)
+
+func TestSourceChain(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/tilt/missing":
+			w.WriteHeader(http.StatusNotFound)
+		case "/tilt/broken":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			_, _ = w.Write([]byte(`{"dataDisclosed": [{"category": "endpoint"}]}`))
+		}
+	}))
+	defer srv.Close()
+
+	cfg := NewFactory().CreateDefaultConfig().(*Config)
+	cfg.ServiceMap = map[string]string{"svc": srv.Listener.Addr().String()}
+	cfg.Sources = []SourceConfig{
+		{Type: sourceTypeStatic, Hosts: []string{"override"}, Document: map[string]interface{}{
+			"dataDisclosed": []interface{}{map[string]interface{}{"category": "override"}},
+		}},
+		{Type: sourceTypeHTTP},
+		{Type: sourceTypeStatic, Document: map[string]interface{}{
+			"dataDisclosed": []interface{}{map[string]interface{}{"category": "default"}},
+		}},
+	}
+	require.NoError(t, cfg.Validate())
+
+	chain, err := newSourceChain(componenttest.NewNopProcessorCreateSettings(), cfg, nil)
+	require.NoError(t, err)
+	require.NoError(t, chain.Start(context.Background(), componenttest.NewNopHost()))
+	defer func() { assert.NoError(t, chain.Shutdown(context.Background())) }()
+
+	tests := []struct {
+		host     string
+		path     string
+		category string
+	}{
+		{host: "override:8080", path: "/users", category: "override"},
+		{host: "svc", path: "/users", category: "endpoint"},
+		{host: "svc", path: "/missing", category: "default"},
+		{host: "svc", path: "/broken", category: "default"},
+	}
+	for _, tt := range tests {
+		spec, err := chain.Resolve(context.Background(), tt.host, tt.path)
+		require.NoError(t, err)
+		assert.Equal(t, tt.category, spec.DataDisclosed[0].Category, tt.host+tt.path)
+	}
+
+	chain = chain[:2]
+	_, err = chain.Resolve(context.Background(), "svc", "/missing")
+	assert.True(t, errors.Is(err, ErrNotFound))
+	_, err = chain.Resolve(context.Background(), "svc", "/broken")
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, ErrNotFound))
+}
+
+func TestSourceConfigValidate(t *testing.T) {
+	assert.NoError(t, (&SourceConfig{Type: sourceTypeHTTP}).Validate())
+	assert.Error(t, (&SourceConfig{Type: "ftp"}).Validate())
+	assert.Error(t, (&SourceConfig{Type: sourceTypeFiles}).Validate())
+	assert.Error(t, (&SourceConfig{Type: sourceTypeStatic}).Validate())
+}
@@ -0,0 +1,43 @@
+package transparencyprocessor
+
+import (
+	"sync"
+
+	"github.com/golang/groupcache/lru"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+// spanLinks remembers the attributes key of recently processed spans, so that
+// log records without HTTP attributes can be enriched through their trace and
+// span IDs. The least recently used spans are evicted once maxEntries is reached.
+type spanLinks struct {
+	mu    sync.Mutex
+	cache *lru.Cache
+}
+
+func newSpanLinks(maxEntries int) *spanLinks {
+	return &spanLinks{cache: lru.New(maxEntries)}
+}
+
+type spanLinkKey struct {
+	traceID pcommon.TraceID
+	spanID  pcommon.SpanID
+}
+
+// add links the span to key.
+func (l *spanLinks) add(traceID pcommon.TraceID, spanID pcommon.SpanID, key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.cache.Add(spanLinkKey{traceID, spanID}, key)
+}
+
+// get returns the attributes key the span was linked to.
+func (l *spanLinks) get(traceID pcommon.TraceID, spanID pcommon.SpanID) (string, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	v, ok := l.cache.Get(spanLinkKey{traceID, spanID})
+	if !ok {
+		return "", false
+	}
+	return v.(string), true
+}
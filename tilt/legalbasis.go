@@ -0,0 +1,61 @@
+package tilt
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	legalBasisRegulation = regexp.MustCompile(`(regulation\s*)?\(?eu\)?\s*(no\.?\s*)?2016/679`)
+	legalBasisWords      = regexp.MustCompile(`\b(gdpr|dsgvo|bdsg|articles?|artikel|art|absatz|abs|paragraphs?|para|litera|lit|point|nr|no|of|the)\b\.?`)
+	legalBasisSentence   = regexp.MustCompile(`\b(sentence|satz|s)\b\.?\s*[0-9]+`)
+	legalBasisRoman      = regexp.MustCompile(`^([^0-9a-z]*[0-9]+[^0-9a-z]*)(viii|vii|vi|iv|v|ix|x|iii|ii|i)\b`)
+	legalBasisTokens     = regexp.MustCompile(`[0-9]+|[a-z]`)
+	legalBasisSeparators = regexp.MustCompile(`^[\s.,()\[\]§_/-]*$`)
+)
+
+// NormalizeLegalBasis maps a legal basis reference to its canonical ID of the
+// form LAW-ARTICLE[-PARAGRAPH][-POINT], e.g. "Art. 6(1)(a) GDPR", "Art. 6
+// Abs. 1 lit. a DSGVO" and "6.1.a" all become "GDPR-6-1-a", and "§ 26 Abs. 1
+// BDSG" becomes "BDSG-26-1". Sentences as in "Abs. 1 S. 1" are dropped and
+// Roman paragraphs as in "Art. 6 I a DSGVO" are read as numbers. References
+// without a law refer to the GDPR. It returns false if the reference cannot
+// be parsed.
+func NormalizeLegalBasis(reference string) (string, bool) {
+	s := strings.ToLower(reference)
+	law := "GDPR"
+	if strings.Contains(s, "bdsg") {
+		law = "BDSG"
+	}
+	s = legalBasisRegulation.ReplaceAllString(s, " ")
+	s = legalBasisWords.ReplaceAllString(s, " ")
+	s = legalBasisSentence.ReplaceAllString(s, " ")
+	if m := legalBasisRoman.FindStringSubmatchIndex(s); m != nil {
+		s = s[:m[3]] + " " + romanNumerals[s[m[4]:m[5]]] + s[m[5]:]
+	}
+
+	tokens := legalBasisTokens.FindAllString(s, -1)
+	if !legalBasisSeparators.MatchString(legalBasisTokens.ReplaceAllString(s, " ")) {
+		return "", false
+	}
+	// article, then an optional paragraph, then an optional point.
+	if len(tokens) == 0 || len(tokens) > 3 || !isNumber(tokens[0]) {
+		return "", false
+	}
+	for i, t := range tokens[1:] {
+		last := i == len(tokens)-2
+		if !isNumber(t) && (!last || (i == 0 && law == "BDSG")) {
+			return "", false
+		}
+	}
+	return law + "-" + strings.Join(tokens, "-"), true
+}
+
+var romanNumerals = map[string]string{
+	"i": "1", "ii": "2", "iii": "3", "iv": "4", "v": "5",
+	"vi": "6", "vii": "7", "viii": "8", "ix": "9", "x": "10",
+}
+
+func isNumber(token string) bool {
+	return token[0] >= '0' && token[0] <= '9'
+}
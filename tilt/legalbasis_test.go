@@ -0,0 +1,50 @@
+package tilt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeLegalBasis(t *testing.T) {
+	tests := []struct {
+		reference string
+		want      string
+	}{
+		{reference: "GDPR-6-1-a", want: "GDPR-6-1-a"},
+		{reference: "gdpr 6 1 a", want: "GDPR-6-1-a"},
+		{reference: "DSGVO-6-1-a", want: "GDPR-6-1-a"},
+		{reference: "Art. 6(1)(a) GDPR", want: "GDPR-6-1-a"},
+		{reference: "Article 6 (1) (a) GDPR", want: "GDPR-6-1-a"},
+		{reference: "Art. 6 para. 1 lit. a GDPR", want: "GDPR-6-1-a"},
+		{reference: "Art. 6 Abs. 1 lit. a DSGVO", want: "GDPR-6-1-a"},
+		{reference: "Art. 6(1)(f) of Regulation (EU) 2016/679", want: "GDPR-6-1-f"},
+		{reference: "Article 6(1)(a) of the GDPR", want: "GDPR-6-1-a"},
+		{reference: "Art. 6 Abs. 1 S. 1 lit. a DSGVO", want: "GDPR-6-1-a"},
+		{reference: "Art. 6 Abs. 1 Satz 1 lit. f DSGVO", want: "GDPR-6-1-f"},
+		{reference: "Art. 6 I a DSGVO", want: "GDPR-6-1-a"},
+		{reference: "Art. 6 I S. 1 lit. b DSGVO", want: "GDPR-6-1-b"},
+		{reference: "Art. 9 II h DSGVO", want: "GDPR-9-2-h"},
+		{reference: "§ 26 I BDSG", want: "BDSG-26-1"},
+		{reference: "6.1.a", want: "GDPR-6-1-a"},
+		{reference: "Art. 9(2)(h) GDPR", want: "GDPR-9-2-h"},
+		{reference: "Art. 9(2)(i) GDPR", want: "GDPR-9-2-i"},
+		{reference: "Art. 22 GDPR", want: "GDPR-22"},
+		{reference: "§ 26 Abs. 1 BDSG", want: "BDSG-26-1"},
+		{reference: "§26(1) BDSG", want: "BDSG-26-1"},
+		{reference: "BDSG-26-1", want: "BDSG-26-1"},
+		{reference: "consent"},
+		{reference: "legitimate interest (6.1.f)"},
+		{reference: "a.6.1"},
+		{reference: "6.a.1"},
+		{reference: "6.1.a.b"},
+		{reference: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.reference, func(t *testing.T) {
+			got, ok := NormalizeLegalBasis(tt.reference)
+			assert.Equal(t, tt.want != "", ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
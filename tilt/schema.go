@@ -0,0 +1,63 @@
+package tilt
+
+import (
+	_ "embed" // embeds the TILT schema
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// schemaJSON is a bundled copy of the TILT JSON Schema, so that documents can
+// be validated without network access.
+//
+//go:embed tilt-schema.json
+var schemaJSON string
+
+var schema = jsonschema.MustCompileString("tilt-schema.json", schemaJSON)
+
+// ValidationError lists the violations of the TILT schema found in a document.
+type ValidationError struct {
+	// Errors holds one message per violation, prefixed with the JSON pointer
+	// of the offending value.
+	Errors []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid TILT document: %s", strings.Join(e.Errors, "; "))
+}
+
+// Validate checks a JSON encoded document against the TILT schema. If the
+// document is valid JSON but violates the schema, a *ValidationError is returned.
+func Validate(b []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return fmt.Errorf("error decoding TILT document: %w", err)
+	}
+	err := schema.Validate(v)
+	if err == nil {
+		return nil
+	}
+	ve, ok := err.(*jsonschema.ValidationError)
+	if !ok {
+		return err
+	}
+	res := &ValidationError{}
+	var collect func(*jsonschema.ValidationError)
+	collect = func(e *jsonschema.ValidationError) {
+		if len(e.Causes) == 0 {
+			loc := e.InstanceLocation
+			if loc == "" {
+				loc = "/"
+			}
+			res.Errors = append(res.Errors, fmt.Sprintf("%s: %s", loc, e.Message))
+			return
+		}
+		for _, c := range e.Causes {
+			collect(c)
+		}
+	}
+	collect(ve)
+	return res
+}
@@ -0,0 +1,242 @@
+// Package tilt models documents of the Transparency Information Language and
+// Toolkit (TILT), which describe how a service processes personal data in the
+// terms of the GDPR transparency obligations (Art. 13, 14 and 15).
+package tilt
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Document is a TILT document.
+type Document struct {
+	Meta                           Meta                     `json:"meta"`
+	Controller                     Controller               `json:"controller"`
+	DataProtectionOfficer          DataProtectionOfficer    `json:"dataProtectionOfficer"`
+	DataDisclosed                  []DataDisclosed          `json:"dataDisclosed"`
+	ThirdCountryTransfers          []ThirdCountryTransfer   `json:"thirdCountryTransfers"`
+	AccessAndDataPortability       AccessAndDataPortability `json:"accessAndDataPortability"`
+	Sources                        []Source                 `json:"sources"`
+	RightToInformation             Right                    `json:"rightToInformation"`
+	RightToRectificationOrDeletion Right                    `json:"rightToRectificationOrDeletion"`
+	RightToDataPortability         Right                    `json:"rightToDataPortability"`
+	RightToWithdrawConsent         Right                    `json:"rightToWithdrawConsent"`
+	RightToComplain                RightToComplain          `json:"rightToComplain"`
+	AutomatedDecisionMaking        AutomatedDecisionMaking  `json:"automatedDecisionMaking"`
+	ChangesOfPurpose               []ChangeOfPurpose        `json:"changesOfPurpose"`
+
+	// Routes lists route templates like /users/{id} the document applies to.
+	// It is an extension, not part of the TILT schema.
+	Routes []string `json:"routes"`
+
+	// Raw holds the JSON the document was decoded from.
+	Raw json.RawMessage `json:"-"`
+}
+
+// Meta describes the document itself.
+type Meta struct {
+	ID       string `json:"_id"`
+	Name     string `json:"name"`
+	Created  string `json:"created"`
+	Modified string `json:"modified"`
+	Version  int    `json:"version"`
+	Language string `json:"language"`
+	Status   string `json:"status"`
+	URL      string `json:"url"`
+	Hash     string `json:"_hash"`
+}
+
+// Controller is the entity responsible for the processing (Art. 13(1)(a) GDPR).
+type Controller struct {
+	Name           string         `json:"name"`
+	Division       string         `json:"division"`
+	Address        string         `json:"address"`
+	Country        string         `json:"country"`
+	Representative Representative `json:"representative"`
+}
+
+// Representative is a contact person of a controller or recipient.
+type Representative struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+	Phone string `json:"phone"`
+}
+
+// DataProtectionOfficer holds the contact details of the data protection
+// officer (Art. 13(1)(b) GDPR).
+type DataProtectionOfficer struct {
+	Name    string `json:"name"`
+	Address string `json:"address"`
+	Country string `json:"country"`
+	Email   string `json:"email"`
+	Phone   string `json:"phone"`
+}
+
+// DataDisclosed describes one category of personal data and how it is processed.
+type DataDisclosed struct {
+	ID                  string               `json:"_id"`
+	Category            string               `json:"category"`
+	Purposes            []Purpose            `json:"purposes"`
+	LegalBases          []LegalBasis         `json:"legalBases"`
+	LegitimateInterests []LegitimateInterest `json:"legitimateInterests"`
+	Recipients          []Recipient          `json:"recipients"`
+	Storage             []Storage            `json:"storage"`
+	NonDisclosure       NonDisclosure        `json:"nonDisclosure"`
+}
+
+// Purpose is a purpose of the processing (Art. 13(1)(c) GDPR).
+type Purpose struct {
+	Purpose     string `json:"purpose"`
+	Description string `json:"description"`
+}
+
+// LegalBasis is a legal basis of the processing, e.g. "GDPR-6-1-a".
+type LegalBasis struct {
+	Reference   string `json:"reference"`
+	Description string `json:"description"`
+}
+
+// LegitimateInterest states whether the processing is based on legitimate
+// interests (Art. 13(1)(d) GDPR).
+type LegitimateInterest struct {
+	Exists    bool   `json:"exists"`
+	Reasoning string `json:"reasoning"`
+}
+
+// Recipient receives the data (Art. 13(1)(e) GDPR).
+type Recipient struct {
+	Name           string         `json:"name"`
+	Division       string         `json:"division"`
+	Address        string         `json:"address"`
+	Country        string         `json:"country"`
+	Representative Representative `json:"representative"`
+	Category       string         `json:"category"`
+}
+
+// Storage describes how long the data is stored (Art. 13(2)(a) GDPR).
+type Storage struct {
+	Temporal              []Temporal `json:"temporal"`
+	PurposeConditional    []string   `json:"purposeConditional"`
+	LegalBasisConditional []string   `json:"legalBasisConditional"`
+	AggregationFunction   string     `json:"aggregationFunction"`
+}
+
+// Temporal is a storage period, TTL holds an ISO 8601 duration.
+type Temporal struct {
+	Description string `json:"description"`
+	TTL         string `json:"ttl"`
+}
+
+// NonDisclosure describes the consequences of not providing the data
+// (Art. 13(2)(e) GDPR).
+type NonDisclosure struct {
+	LegalRequirement      bool   `json:"legalRequirement"`
+	ContractualRegulation bool   `json:"contractualRegulation"`
+	ObligationToProvide   bool   `json:"obligationToProvide"`
+	Consequences          string `json:"consequences"`
+}
+
+// ThirdCountryTransfer describes a transfer to a country outside the EU/EEA
+// (Art. 13(1)(f) GDPR).
+type ThirdCountryTransfer struct {
+	Country                                        string       `json:"country"`
+	AdequacyDecision                               Availability `json:"adequacyDecision"`
+	AppropriateGuarantees                          Availability `json:"appropriateGuarantees"`
+	PresenceOfEnforcableRightsAndEffectiveRemedies Availability `json:"presenceOfEnforcableRightsAndEffectiveRemedies"`
+	StandardDataProtectionClause                   Availability `json:"standardDataProtectionClause"`
+}
+
+// Availability states whether a safeguard is available.
+type Availability struct {
+	Available   bool   `json:"available"`
+	Description string `json:"description"`
+}
+
+// Right describes how a data subject right can be exercised.
+type Right struct {
+	Available               bool     `json:"available"`
+	Description             string   `json:"description"`
+	URL                     string   `json:"url"`
+	Email                   string   `json:"email"`
+	IdentificationEvidences []string `json:"identificationEvidences"`
+}
+
+// AccessAndDataPortability describes the right of access (Art. 15 GDPR).
+type AccessAndDataPortability struct {
+	Right
+	AdministrativeFee AdministrativeFee `json:"administrativeFee"`
+	DataFormats       []string          `json:"dataFormats"`
+}
+
+// AdministrativeFee is the fee charged for further copies of the data.
+type AdministrativeFee struct {
+	Amount   float64 `json:"amount"`
+	Currency string  `json:"currency"`
+}
+
+// RightToComplain describes the right to lodge a complaint with a supervisory
+// authority (Art. 13(2)(d) GDPR).
+type RightToComplain struct {
+	Right
+	SupervisoryAuthority SupervisoryAuthority `json:"supervisoryAuthority"`
+}
+
+// SupervisoryAuthority holds the contact details of a supervisory authority.
+type SupervisoryAuthority struct {
+	Name    string `json:"name"`
+	Address string `json:"address"`
+	Country string `json:"country"`
+	Email   string `json:"email"`
+	Phone   string `json:"phone"`
+}
+
+// Source describes where data not collected from the data subject originates
+// (Art. 14(2)(f) GDPR).
+type Source struct {
+	ID           string        `json:"_id"`
+	DataCategory string        `json:"dataCategory"`
+	Sources      []SourceEntry `json:"sources"`
+}
+
+// SourceEntry is one origin of a data category.
+type SourceEntry struct {
+	Description       string `json:"description"`
+	URL               string `json:"url"`
+	PubliclyAvailable bool   `json:"publiclyAvailable"`
+}
+
+// AutomatedDecisionMaking describes automated decisions including profiling
+// (Art. 13(2)(f) GDPR).
+type AutomatedDecisionMaking struct {
+	InUse                   bool   `json:"inUse"`
+	LogicInvolved           string `json:"logicInvolved"`
+	ScopeAndIntendedEffects string `json:"scopeAndIntendedEffects"`
+}
+
+// ChangeOfPurpose announces a planned change of the purposes (Art. 13(3) GDPR).
+type ChangeOfPurpose struct {
+	Description            string   `json:"description"`
+	AffectedDataCategories []string `json:"affectedDataCategories"`
+	PlannedDateOfChange    string   `json:"plannedDateOfChange"`
+	URLOfNewVersion        string   `json:"urlOfNewVersion"`
+}
+
+// Decode reads a JSON encoded TILT document from r.
+func Decode(r io.Reader) (*Document, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("error reading TILT document: %w", err)
+	}
+	return Unmarshal(b)
+}
+
+// Unmarshal parses a JSON encoded TILT document.
+func Unmarshal(b []byte) (*Document, error) {
+	doc := new(Document)
+	if err := json.Unmarshal(b, doc); err != nil {
+		return nil, fmt.Errorf("error decoding TILT document: %w", err)
+	}
+	doc.Raw = b
+	return doc, nil
+}
@@ -0,0 +1,76 @@
+package tilt
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecode(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "tilt.json"))
+	require.NoError(t, err)
+	defer f.Close()
+
+	doc, err := Decode(f)
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, doc.Meta.Version)
+	assert.Equal(t, "mindtastic GmbH", doc.Controller.Name)
+	assert.Equal(t, "Erika Mustermann", doc.Controller.Representative.Name)
+	assert.Equal(t, "dpo@mindtastic.example", doc.DataProtectionOfficer.Email)
+
+	require.Len(t, doc.DataDisclosed, 1)
+	d := doc.DataDisclosed[0]
+	assert.Equal(t, "Health data", d.Category)
+	assert.Equal(t, []Purpose{{Purpose: "Mood tracking", Description: "Show the mood history to the user"}}, d.Purposes)
+	assert.Equal(t, "GDPR-9-2-a", d.LegalBases[0].Reference)
+	assert.Equal(t, "IE", d.Recipients[0].Country)
+	assert.Equal(t, "P1Y", d.Storage[0].Temporal[0].TTL)
+	assert.Equal(t, "max", d.Storage[0].AggregationFunction)
+	assert.Equal(t, "The diary cannot be used.", d.NonDisclosure.Consequences)
+
+	require.Len(t, doc.ThirdCountryTransfers, 1)
+	assert.Equal(t, "US", doc.ThirdCountryTransfers[0].Country)
+	assert.True(t, doc.ThirdCountryTransfers[0].AdequacyDecision.Available)
+
+	assert.True(t, doc.AccessAndDataPortability.Available)
+	assert.Equal(t, []string{"JSON", "PDF"}, doc.AccessAndDataPortability.DataFormats)
+	assert.Equal(t, "EUR", doc.AccessAndDataPortability.AdministrativeFee.Currency)
+	assert.Equal(t, "Entered by the user", doc.Sources[0].Sources[0].Description)
+	assert.True(t, doc.RightToWithdrawConsent.Available)
+	assert.Equal(t, "DE", doc.RightToComplain.SupervisoryAuthority.Country)
+	assert.False(t, doc.AutomatedDecisionMaking.InUse)
+	assert.Equal(t, []string{"Health data"}, doc.ChangesOfPurpose[0].AffectedDataCategories)
+}
+
+func TestUnmarshalError(t *testing.T) {
+	_, err := Unmarshal([]byte(`{"dataDisclosed": {}}`))
+	assert.Error(t, err)
+	_, err = Decode(strings.NewReader(`{`))
+	assert.Error(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	b, err := os.ReadFile(filepath.Join("testdata", "tilt.json"))
+	require.NoError(t, err)
+	assert.NoError(t, Validate(b))
+
+	doc, err := Unmarshal(b)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(b), string(doc.Raw))
+
+	typo := strings.Replace(string(b), `"legalBases"`, `"legalbasis"`, 1)
+	err = Validate([]byte(typo))
+	require.Error(t, err)
+	var ve *ValidationError
+	require.ErrorAs(t, err, &ve)
+	assert.Len(t, ve.Errors, 1)
+	assert.Contains(t, ve.Errors[0], "/dataDisclosed/0")
+	assert.Contains(t, ve.Errors[0], "legalBases")
+
+	assert.Error(t, Validate([]byte(`{`)))
+}
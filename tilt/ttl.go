@@ -0,0 +1,84 @@
+package tilt
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	day   = 24 * time.Hour
+	week  = 7 * day
+	month = 30 * day
+	year  = 365 * day
+)
+
+var (
+	isoDuration = regexp.MustCompile(`^P(?:(\d+(?:[.,]\d+)?)Y)?(?:(\d+(?:[.,]\d+)?)M)?(?:(\d+(?:[.,]\d+)?)W)?(?:(\d+(?:[.,]\d+)?)D)?(?:T(?:(\d+(?:[.,]\d+)?)H)?(?:(\d+(?:[.,]\d+)?)M)?(?:(\d+(?:[.,]\d+)?)S)?)?$`)
+	isoUnits    = []time.Duration{year, month, week, day, time.Hour, time.Minute, time.Second}
+
+	textDuration  = regexp.MustCompile(`(\d+(?:[.,]\d+)?)\s*([a-z]+)`)
+	textSeparator = regexp.MustCompile(`^(?:\s|,|and|und)*$`)
+	textUnits     = map[string]time.Duration{
+		"y": year, "yr": year, "yrs": year, "year": year, "years": year, "jahr": year, "jahre": year, "jahren": year,
+		"mo": month, "month": month, "months": month, "monat": month, "monate": month, "monaten": month,
+		"w": week, "wk": week, "wks": week, "week": week, "weeks": week, "woche": week, "wochen": week,
+		"d": day, "day": day, "days": day, "tag": day, "tage": day, "tagen": day,
+		"h": time.Hour, "hr": time.Hour, "hrs": time.Hour, "hour": time.Hour, "hours": time.Hour, "stunde": time.Hour, "stunden": time.Hour,
+		"min": time.Minute, "mins": time.Minute, "minute": time.Minute, "minutes": time.Minute, "minuten": time.Minute,
+		"s": time.Second, "sec": time.Second, "secs": time.Second, "second": time.Second, "seconds": time.Second, "sekunde": time.Second, "sekunden": time.Second,
+	}
+)
+
+// ParseTTL parses the TTL of a storage period. It accepts ISO 8601 durations
+// like "P1Y6M" as well as free text like "30 days" or "1 year and 6 months".
+// Years count as 365 days and months as 30 days. It returns false if ttl is
+// not a positive duration in one of these forms.
+func ParseTTL(ttl string) (time.Duration, bool) {
+	s := strings.ToUpper(strings.TrimSpace(ttl))
+	if m := isoDuration.FindStringSubmatch(s); m != nil {
+		// The pattern also matches the invalid "P" and "P…T" without designators.
+		if s == "P" || strings.HasSuffix(s, "T") {
+			return 0, false
+		}
+		return sumDuration(m[1:], isoUnits)
+	}
+
+	s = strings.ToLower(s)
+	matches := textDuration.FindAllStringSubmatch(s, -1)
+	if len(matches) == 0 || !textSeparator.MatchString(textDuration.ReplaceAllString(s, "")) {
+		return 0, false
+	}
+	values := make([]string, 0, len(matches))
+	units := make([]time.Duration, 0, len(matches))
+	for _, m := range matches {
+		unit, ok := textUnits[m[2]]
+		if !ok {
+			return 0, false
+		}
+		values = append(values, m[1])
+		units = append(units, unit)
+	}
+	return sumDuration(values, units)
+}
+
+// sumDuration adds up values[i] * units[i], skipping empty values.
+func sumDuration(values []string, units []time.Duration) (time.Duration, bool) {
+	var total float64
+	for i, v := range values {
+		if v == "" {
+			continue
+		}
+		f, err := strconv.ParseFloat(strings.Replace(v, ",", ".", 1), 64)
+		if err != nil {
+			return 0, false
+		}
+		total += f * float64(units[i])
+	}
+	if total <= 0 || total > math.MaxInt64 {
+		return 0, false
+	}
+	return time.Duration(total), true
+}
@@ -0,0 +1,47 @@
+package tilt
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTTL(t *testing.T) {
+	const day = 24 * time.Hour
+	tests := []struct {
+		ttl  string
+		want time.Duration
+	}{
+		{ttl: "P1Y", want: 365 * day},
+		{ttl: "P1Y6M", want: 545 * day},
+		{ttl: "P2W", want: 14 * day},
+		{ttl: "P30D", want: 30 * day},
+		{ttl: "PT36H", want: 36 * time.Hour},
+		{ttl: "P1DT12H30M", want: 36*time.Hour + 30*time.Minute},
+		{ttl: "PT0.5S", want: 500 * time.Millisecond},
+		{ttl: "p10d", want: 10 * day},
+		{ttl: "30 days", want: 30 * day},
+		{ttl: "1 year and 6 months", want: 545 * day},
+		{ttl: "2 Wochen", want: 14 * day},
+		{ttl: "10 Jahre", want: 3650 * day},
+		{ttl: "24h", want: 24 * time.Hour},
+		{ttl: "1,5 years", want: 547*day + 12*time.Hour},
+		{ttl: "P"},
+		{ttl: "PT"},
+		{ttl: "P0D"},
+		{ttl: "P1H"},
+		{ttl: "forever"},
+		{ttl: "until the contract ends"},
+		{ttl: "30 days after the contract ends"},
+		{ttl: "3 fortnights"},
+		{ttl: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ttl, func(t *testing.T) {
+			got, ok := ParseTTL(tt.ttl)
+			assert.Equal(t, tt.want != 0, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
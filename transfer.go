@@ -0,0 +1,165 @@
+package transparencyprocessor
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	conventions "go.opentelemetry.io/collector/semconv/v1.6.1"
+)
+
+const (
+	// attrTransferCountry is the country outside the EU/EEA the callee of a
+	// span runs in, attrTransferUndeclared is set if its TILT document does
+	// not declare a transfer to that country.
+	attrTransferCountry    = "tilt.transfer.country"
+	attrTransferUndeclared = "tilt.transfer.undeclared"
+)
+
+// eea holds the member states of the European Economic Area. Transfers to
+// other countries are third-country transfers (Art. 44 GDPR).
+var eea = map[string]struct{}{
+	"AT": {}, "BE": {}, "BG": {}, "HR": {}, "CY": {}, "CZ": {}, "DK": {}, "EE": {}, "FI": {}, "FR": {},
+	"DE": {}, "GR": {}, "HU": {}, "IE": {}, "IT": {}, "LV": {}, "LT": {}, "LU": {}, "MT": {}, "NL": {},
+	"PL": {}, "PT": {}, "RO": {}, "SK": {}, "SI": {}, "ES": {}, "SE": {}, "IS": {}, "LI": {}, "NO": {},
+}
+
+// defaultRegions maps the regions of the major cloud providers to the ISO
+// 3166-1 alpha-2 code of the country they are located in.
+var defaultRegions = map[string]string{
+	// aws
+	"us-east-1": "US", "us-east-2": "US", "us-west-1": "US", "us-west-2": "US", "ca-central-1": "CA",
+	"eu-central-1": "DE", "eu-central-2": "CH", "eu-west-1": "IE", "eu-west-2": "GB", "eu-west-3": "FR",
+	"eu-north-1": "SE", "eu-south-1": "IT", "eu-south-2": "ES", "ap-northeast-1": "JP", "ap-northeast-2": "KR",
+	"ap-northeast-3": "JP", "ap-southeast-1": "SG", "ap-southeast-2": "AU", "ap-southeast-3": "ID",
+	"ap-south-1": "IN", "ap-east-1": "HK", "sa-east-1": "BR", "me-south-1": "BH", "me-central-1": "AE",
+	"af-south-1": "ZA",
+	// gcp
+	"europe-west1": "BE", "europe-west2": "GB", "europe-west3": "DE", "europe-west4": "NL", "europe-west6": "CH",
+	"europe-west8": "IT", "europe-west9": "FR", "europe-north1": "FI", "europe-central2": "PL",
+	"europe-southwest1": "ES", "us-central1": "US", "us-east1": "US", "us-east4": "US", "us-west1": "US",
+	"us-west2": "US", "us-west3": "US", "us-west4": "US", "northamerica-northeast1": "CA",
+	"southamerica-east1": "BR", "asia-east1": "TW", "asia-east2": "HK", "asia-northeast1": "JP",
+	"asia-northeast3": "KR", "asia-south1": "IN", "asia-southeast1": "SG", "australia-southeast1": "AU",
+	// azure
+	"westeurope": "NL", "northeurope": "IE", "germanywestcentral": "DE", "francecentral": "FR",
+	"swedencentral": "SE", "norwayeast": "NO", "switzerlandnorth": "CH", "uksouth": "GB", "ukwest": "GB",
+	"eastus": "US", "eastus2": "US", "westus": "US", "westus2": "US", "centralus": "US", "canadacentral": "CA",
+	"brazilsouth": "BR", "japaneast": "JP", "southeastasia": "SG", "eastasia": "HK", "australiaeast": "AU",
+	"centralindia": "IN", "koreacentral": "KR",
+}
+
+// countryNames maps the English names of the EU/EEA member states and of the
+// countries in defaultRegions to their codes, since TILT documents and
+// configurations often name a country instead of using its code.
+var countryNames = map[string]string{
+	"austria": "AT", "belgium": "BE", "bulgaria": "BG", "croatia": "HR", "cyprus": "CY",
+	"czech republic": "CZ", "czechia": "CZ", "denmark": "DK", "estonia": "EE", "finland": "FI",
+	"france": "FR", "germany": "DE", "greece": "GR", "hungary": "HU", "ireland": "IE", "italy": "IT",
+	"latvia": "LV", "lithuania": "LT", "luxembourg": "LU", "malta": "MT", "netherlands": "NL",
+	"the netherlands": "NL", "poland": "PL", "portugal": "PT", "romania": "RO", "slovakia": "SK",
+	"slovenia": "SI", "spain": "ES", "sweden": "SE", "iceland": "IS", "liechtenstein": "LI", "norway": "NO",
+	"united states": "US", "usa": "US", "united states of america": "US", "canada": "CA",
+	"switzerland": "CH", "united kingdom": "GB", "uk": "GB", "great britain": "GB", "japan": "JP",
+	"south korea": "KR", "republic of korea": "KR", "korea": "KR", "singapore": "SG", "australia": "AU",
+	"indonesia": "ID", "india": "IN", "hong kong": "HK", "brazil": "BR", "bahrain": "BH",
+	"united arab emirates": "AE", "south africa": "ZA", "taiwan": "TW", "china": "CN",
+}
+
+// countryCode returns the ISO 3166-1 alpha-2 code of country, which is either
+// a code or one of countryNames.
+func countryCode(country string) string {
+	country = strings.TrimSpace(country)
+	if code, ok := countryNames[strings.ToLower(country)]; ok {
+		return code
+	}
+	return strings.ToUpper(country)
+}
+
+// transfers locates the callees of spans.
+type transfers struct {
+	hosts   map[string]string
+	regions map[string]string
+}
+
+func newTransfers(cfg TransfersConfig) transfers {
+	t := transfers{
+		hosts:   make(map[string]string, len(cfg.Hosts)),
+		regions: make(map[string]string, len(defaultRegions)+len(cfg.Regions)),
+	}
+	for region, country := range defaultRegions {
+		t.regions[region] = country
+	}
+	for region, country := range cfg.Regions {
+		t.regions[region] = countryCode(country)
+	}
+	for host, country := range cfg.Hosts {
+		t.hosts[host] = countryCode(country)
+	}
+	return t
+}
+
+// country returns the country the callee at host runs in. Configured hosts,
+// with or without port, take precedence over the cloud.region attributes of
+// the span and of its resource. Regions are looked up as "provider/region" first, then as
+// "region". An unknown region of the span falls back to that of the resource.
+func (t transfers) country(host string, span, resource pcommon.Map) (string, bool) {
+	if country, ok := t.hosts[host]; ok {
+		return country, true
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		if country, ok := t.hosts[h]; ok {
+			return country, true
+		}
+	}
+	for _, attrs := range []pcommon.Map{span, resource} {
+		region, ok := attrs.Get(conventions.AttributeCloudRegion)
+		if !ok {
+			continue
+		}
+		if provider, ok := attrs.Get(conventions.AttributeCloudProvider); ok {
+			if country, ok := t.regions[provider.AsString()+"/"+region.AsString()]; ok {
+				return country, true
+			}
+		}
+		if country, ok := t.regions[region.AsString()]; ok {
+			return country, true
+		}
+	}
+	return "", false
+}
+
+// undeclaredTransfer reports whether a transfer to country leaves the EU/EEA
+// without being declared in attr. Without a resolved document nothing is
+// known about the declared transfers, so none is reported.
+func undeclaredTransfer(country string, attr tiltAttributes) bool {
+	if _, ok := eea[country]; ok || !attr.resolved {
+		return false
+	}
+	for _, c := range attr.thirdCountries {
+		if c == country {
+			return false
+		}
+	}
+	return true
+}
+
+// Validate checks if the transfers configuration is valid.
+func (cfg *TransfersConfig) Validate() error {
+	for region, country := range cfg.Regions {
+		if !isCountryCode(countryCode(country)) {
+			return fmt.Errorf("regions: unknown country %q for region %q", country, region)
+		}
+	}
+	for host, country := range cfg.Hosts {
+		if !isCountryCode(countryCode(country)) {
+			return fmt.Errorf("hosts: unknown country %q for host %q", country, host)
+		}
+	}
+	return nil
+}
+
+func isCountryCode(code string) bool {
+	return len(code) == 2 && code[0] >= 'A' && code[0] <= 'Z' && code[1] >= 'A' && code[1] <= 'Z'
+}
@@ -0,0 +1,143 @@
+package transparencyprocessor
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opencensus.io/stats/view"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/config/configtelemetry"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+
+	"github.com/mindtastic/opentelemetry-transparency-processor/tilt"
+)
+
+func TestTransfersCountry(t *testing.T) {
+	tr := newTransfers(TransfersConfig{
+		Regions: map[string]string{"on-prem-1": "Switzerland", "gcp/us-east-1": "CA"},
+		Hosts:   map[string]string{"api.example.com": "us"},
+	})
+
+	tests := []struct {
+		name     string
+		host     string
+		span     map[string]interface{}
+		resource map[string]interface{}
+		want     string
+	}{
+		{name: "no geography"},
+		{name: "configured host", host: "api.example.com", resource: map[string]interface{}{"cloud.region": "eu-central-1"}, want: "US"},
+		{name: "configured host with port", host: "api.example.com:443", want: "US"},
+		{name: "unknown host with port", host: "api.example.org:443"},
+		{name: "built-in region", resource: map[string]interface{}{"cloud.region": "eu-central-1"}, want: "DE"},
+		{name: "configured region", resource: map[string]interface{}{"cloud.region": "on-prem-1"}, want: "CH"},
+		{name: "provider region", resource: map[string]interface{}{"cloud.provider": "gcp", "cloud.region": "us-east-1"}, want: "CA"},
+		{name: "other provider", resource: map[string]interface{}{"cloud.provider": "aws", "cloud.region": "us-east-1"}, want: "US"},
+		{name: "span overrides resource", span: map[string]interface{}{"cloud.region": "westeurope"}, resource: map[string]interface{}{"cloud.region": "eastus"}, want: "NL"},
+		{name: "unknown span region", span: map[string]interface{}{"cloud.region": "mars-1"}, resource: map[string]interface{}{"cloud.region": "eastus"}, want: "US"},
+		{name: "unknown region", resource: map[string]interface{}{"cloud.region": "mars-1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tr.country(tt.host, pcommon.NewMapFromRaw(tt.span), pcommon.NewMapFromRaw(tt.resource))
+			assert.Equal(t, tt.want != "", ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestUndeclaredTransfer(t *testing.T) {
+	doc, err := tilt.Unmarshal([]byte(`{"thirdCountryTransfers": [{"country": "United States"}, {"country": "ch"}]}`))
+	require.NoError(t, err)
+	attr := newTiltAttributes(doc)
+	assert.Equal(t, []string{"US", "CH"}, attr.thirdCountries)
+
+	assert.False(t, undeclaredTransfer("DE", attr), "EEA")
+	assert.False(t, undeclaredTransfer("NO", attr), "EEA")
+	assert.False(t, undeclaredTransfer("US", attr))
+	assert.False(t, undeclaredTransfer("CH", attr))
+	assert.True(t, undeclaredTransfer("JP", attr))
+	assert.True(t, undeclaredTransfer("US", tiltAttributes{resolved: true}))
+	assert.False(t, undeclaredTransfer("US", tiltAttributes{}), "negative entries declare nothing")
+}
+
+func TestTransfersConfigValidate(t *testing.T) {
+	cfg := NewFactory().CreateDefaultConfig().(*Config)
+	cfg.Transfers.Regions = map[string]string{"on-prem-1": "DE", "on-prem-2": "Japan", "on-prem-fra": "Germany"}
+	assert.NoError(t, cfg.Validate())
+	assert.Equal(t, "DE", newTransfers(cfg.Transfers).regions["on-prem-fra"])
+
+	cfg.Transfers.Regions["on-prem-3"] = "Atlantis"
+	assert.Error(t, cfg.Validate())
+
+	cfg.Transfers.Regions = nil
+	cfg.Transfers.Hosts = map[string]string{"api.example.com": "U.S."}
+	assert.Error(t, cfg.Validate())
+}
+
+func TestProcessTracesTransfers(t *testing.T) {
+	resetViews(t)
+	srv := newTiltServer(t, testTiltDocument)
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.ServiceMap = map[string]string{"testHost": srv.Listener.Addr().String()}
+	set := componenttest.NewNopProcessorCreateSettings()
+	set.MetricsLevel = configtelemetry.LevelBasic
+	tp, err := factory.CreateTracesProcessor(context.Background(), set, cfg, consumertest.NewNop())
+	require.NoError(t, err)
+	tp = startedProcessor(t, tp)
+
+	runIndividualTestCase(t, testCase{
+		name:        "transfers",
+		serviceName: "linkerd-proxy",
+		resourceAttributes: map[string]interface{}{
+			"linkerd.io/proxy-deployment": "linkerd",
+			"cloud.region":                "ap-northeast-1",
+		},
+		inputAttributes: map[string]interface{}{"http.host": "testHost"},
+		expectedAttributes: testEnrichedAttributes(map[string]interface{}{
+			"http.host":                "testHost",
+			"tilt.transfer.country":    "JP",
+			"tilt.transfer.undeclared": true,
+		}),
+	}, tp)
+
+	assert.Eventually(t, func() bool {
+		rows := viewRows(t, statUndeclaredTransfers.Name())
+		d, ok := rows["[country=JP]"]
+		return ok && len(rows) == 1 && d.(*view.SumData).Value >= 1
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestProcessTracesTransfersFailedFetch(t *testing.T) {
+	resetViews(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	t.Cleanup(srv.Close)
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.ServiceMap = map[string]string{"testHost": srv.Listener.Addr().String()}
+	set := componenttest.NewNopProcessorCreateSettings()
+	set.MetricsLevel = configtelemetry.LevelBasic
+	tp, err := factory.CreateTracesProcessor(context.Background(), set, cfg, consumertest.NewNop())
+	require.NoError(t, err)
+	tp = startedProcessor(t, tp)
+
+	resourceAttrs := map[string]interface{}{"linkerd.io/proxy-deployment": "linkerd", "cloud.region": "us-east-1"}
+	attrs := map[string]interface{}{"http.host": "testHost"}
+	require.NoError(t, tp.ConsumeTraces(context.Background(), generateTraceData("linkerd-proxy", "/path", resourceAttrs, attrs)))
+	assert.Eventually(t, func() bool { return len(viewRows(t, statFetchErrors.Name())) == 1 }, time.Second, 10*time.Millisecond)
+
+	td := generateTraceData("linkerd-proxy", "/path", resourceAttrs, attrs)
+	require.NoError(t, tp.ConsumeTraces(context.Background(), td))
+	_, ok := td.ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0).Attributes().Get(attrTransferUndeclared)
+	assert.False(t, ok, "transfers are not flagged without a document")
+	assert.Empty(t, viewRows(t, statUndeclaredTransfers.Name()))
+}
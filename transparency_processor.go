@@ -2,21 +2,29 @@ package transparencyprocessor
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/mindtastic/opentelemetry-transparency-processor/internal/extractor"
+	"github.com/mindtastic/opentelemetry-transparency-processor/internal/filterlog"
+	"github.com/mindtastic/opentelemetry-transparency-processor/internal/filtermetric"
 	"github.com/mindtastic/opentelemetry-transparency-processor/internal/filterspan"
+	"github.com/mindtastic/opentelemetry-transparency-processor/tilt"
+	"go.opencensus.io/tag"
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/config/configtelemetry"
 	"go.opentelemetry.io/collector/consumer"
 	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
 	"go.opentelemetry.io/collector/pdata/ptrace"
 	conventions "go.opentelemetry.io/collector/semconv/v1.6.1"
+	"go.uber.org/multierr"
 	"go.uber.org/zap"
 	"net/http"
-	"net/url"
 	"path"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -25,10 +33,19 @@ var processorCapabilities = consumer.Capabilities{MutatesData: true}
 const (
 	attrCategories          = "tilt.categories"
 	attrLegalBases          = "tilt.legal_bases"
+	attrCanonicalLegalBases = "tilt.legal_bases_canonical"
 	attrLegitimateInterests = "tilt.legitimate_interests"
 	attrStorages            = "tilt.storage_durations"
 	attrPurposes            = "tilt.purposes"
 	attrAutomatedDecision   = "tilt.automated_decision_making"
+	attrValidationErrors    = "tilt.validation_errors"
+	attrMaxStorage          = "tilt.storage.max_seconds"
+	attrRetentionUntil      = "tilt.retention_until"
+
+	// attrDataDisclosed prefixes the indexed attributes and names the span
+	// events of the individual data categories.
+	attrDataDisclosed = "tilt.data_disclosed"
+	attrCategory      = "tilt.category"
 )
 
 type tiltAttributes struct {
@@ -39,38 +56,339 @@ type tiltAttributes struct {
 	storages           []string
 	puproses           []string
 	automatedDecision  bool
+	validationErrors   []string
+	dataDisclosed      []disclosedAttributes
+
+	// thirdCountries holds the codes of the countries outside the EU/EEA
+	// the document declares transfers to.
+	thirdCountries []string
+
+	// maxStorage is the longest of the storages that could be parsed.
+	maxStorage time.Duration
+
+	// canonicalLegalBases holds the normalized legalBases at the same
+	// positions, with an empty string for those that could not be normalized.
+	// unparsedLegalBases lists the latter.
+	canonicalLegalBases []string
+	unparsedLegalBases  []string
+
+	// resolved is set for attributes derived from a document. It is unset in
+	// the negative entries of keys whose document could not be fetched yet.
+	resolved bool
+
+	// document is the TILT document the attributes were derived from.
+	document []byte
+}
+
+// disclosedAttributes holds the attributes of a single data category.
+type disclosedAttributes struct {
+	category            string
+	legalBases          []string
+	canonicalLegalBases []string
+	legitimateInterests []bool
+	storages            []string
+	purposes            []string
 }
 
 type transparencyProcessor struct {
-	logger    *zap.Logger
-	exportCtx context.Context
+	logger *zap.Logger
 
-	timeout time.Duration
+	// exportCtx is created in start and cancelled in shutdown. It bounds all
+	// requests for TILT documents, so none outlives the pipeline.
+	exportCtx context.Context
+	cancel    context.CancelFunc
 
 	telemetryLevel configtelemetry.Level
 
-	serviceMap map[string]string
+	sources    sourceChain
+	validation ValidationMode
+	output     OutputMode
+	meshes     []mesh
+	extractors []string
+	routes     *routes
+	policies   []policy
+	transfers  transfers
+	redactions []redaction
+	tags       []tag.Mutator
+
+	attributesCache *attributesCache
+	refreshAhead    time.Duration
+	refreshInterval time.Duration
+	snapshot        SnapshotConfig
+	prefetch        PrefetchConfig
+	serviceMap      map[string]string
+	ready           int32
+	server          *server
+	graph           *flowGraph
+	fetchQueue      *fetchQueue
+	workers         int
+	done            chan struct{}
+	wg              sync.WaitGroup
+	spanLinks       *spanLinks
 
-	mu              sync.RWMutex
-	attributesCache map[string]tiltAttributes
-	include         filterspan.Matcher
-	exclude         filterspan.Matcher
+	// unparsedLegalBases holds the legal basis references that were counted
+	// as unparsed, so that refreshes do not count them again.
+	unparsedLegalBases sync.Map
+
+	// The processor is shared by the traces, logs and metrics pipelines of a
+	// configuration, it is started and shut down once.
+	startOnce    sync.Once
+	startErr     error
+	shutdownOnce sync.Once
+	shutdownErr  error
+	release      func()
+
+	include    filterspan.Matcher
+	exclude    filterspan.Matcher
+	logInclude filterlog.Matcher
+	logExclude filterlog.Matcher
+
+	metricInclude filtermetric.Matcher
+	metricExclude filtermetric.Matcher
 	//attrProc        *attraction.AttrProc
 }
 
-func newTransparencyProcessor(set component.ProcessorCreateSettings, include, exclude filterspan.Matcher, serviceMap map[string]string) *transparencyProcessor {
+func newTransparencyProcessor(set component.ProcessorCreateSettings, cfg *Config) (*transparencyProcessor, error) {
 	tp := new(transparencyProcessor)
 	tp.logger = set.Logger
-	tp.attributesCache = make(map[string]tiltAttributes)
-	tp.mu = sync.RWMutex{}
-	tp.serviceMap = serviceMap
-	tp.include = include
-	tp.exclude = exclude
+	tp.telemetryLevel = set.MetricsLevel
+	tp.attributesCache = newAttributesCache(cfg.Cache.TTL, newBackoff(cfg.Cache.FailureBackoff))
+	tp.refreshAhead = cfg.Cache.RefreshAhead
+	tp.refreshInterval = cfg.Cache.RefreshInterval
+	tp.snapshot = cfg.Cache.Snapshot
+	tp.prefetch = cfg.Prefetch
+	tp.serviceMap = cfg.ServiceMap
+	tp.server = newServer(cfg.Server, set.TelemetrySettings)
+	tp.server.handle("/ready", tp.handleReady)
+	if cfg.Server.Endpoint != "" {
+		tp.graph = newFlowGraph()
+		tp.server.handle("/graph", tp.graph.handleJSON)
+		tp.server.handle("/graph.dot", tp.graph.handleDOT)
+		tp.server.handle("/cache", tp.handleCache)
+	}
+	tp.fetchQueue = newFetchQueue(cfg.Fetch.QueueSize)
+	tp.workers = cfg.Fetch.Workers
+	tp.done = make(chan struct{})
+	tp.spanLinks = newSpanLinks(cfg.Logs.SpanCacheSize)
+	sources, err := newSourceChain(set, cfg, tp.refetchHosts)
+	if err != nil {
+		return nil, err
+	}
+	tp.sources = sources
+	tp.validation = cfg.Validation
+	tp.output = cfg.Output
+	tp.meshes = newMeshes(cfg.Meshes)
+	tp.extractors = cfg.Extractors
+	tp.routes = newRoutes(cfg.Routes)
+	tp.policies = newPolicies(cfg.Policies)
+	tp.transfers = newTransfers(cfg.Transfers)
+	tp.redactions = newRedactions(cfg.Redaction)
+	tp.tags = []tag.Mutator{tag.Upsert(tagProcessorKey, cfg.ID().String())}
 
-	return tp
+	return tp, nil
+}
+
+func (a *transparencyProcessor) start(ctx context.Context, host component.Host) error {
+	a.startOnce.Do(func() {
+		if a.snapshot.Path != "" {
+			// A missing or broken snapshot only means the cache starts empty.
+			n, err := a.loadSnapshot()
+			if err != nil {
+				a.logger.Warn("error loading cache snapshot", zap.String("path", a.snapshot.Path), zap.Error(err))
+			} else {
+				a.logger.Info("loaded cache snapshot", zap.String("path", a.snapshot.Path), zap.Int("entries", n))
+			}
+		}
+
+		a.exportCtx, a.cancel = context.WithCancel(context.Background())
+		if a.startErr = a.sources.Start(ctx, host); a.startErr != nil {
+			return
+		}
+		if a.startErr = a.server.Start(ctx, host); a.startErr != nil {
+			return
+		}
+
+		a.wg.Add(1 + a.workers)
+		go a.refreshLoop()
+		for i := 0; i < a.workers; i++ {
+			go a.fetchLoop()
+		}
+		if a.snapshot.Path != "" {
+			a.wg.Add(1)
+			go a.snapshotLoop()
+		}
+		if a.prefetch.Enabled {
+			a.wg.Add(1)
+			go a.prefetchAll()
+		} else {
+			atomic.StoreInt32(&a.ready, 1)
+		}
+	})
+	return a.startErr
+}
+
+func (a *transparencyProcessor) shutdown(ctx context.Context) error {
+	a.shutdownOnce.Do(func() {
+		if a.release != nil {
+			a.release()
+		}
+		if a.cancel != nil {
+			a.cancel()
+		}
+		a.shutdownErr = multierr.Append(a.server.Shutdown(ctx), a.sources.Shutdown(ctx))
+		close(a.done)
+		a.wg.Wait()
+		if a.snapshot.Path != "" {
+			a.shutdownErr = multierr.Append(a.shutdownErr, writeSnapshot(a.snapshot.Path, a.attributesCache))
+		}
+	})
+	return a.shutdownErr
+}
+
+// refetchHosts queues a fetch for every cached key of the given hosts.
+func (a *transparencyProcessor) refetchHosts(hosts []string) {
+	for _, h := range hosts {
+		for _, k := range a.attributesCache.keys(h) {
+			if host, path, ok := a.attributesCache.source(k); ok {
+				a.enqueueFetch(host, path)
+			}
+		}
+	}
+}
+
+// refreshLoop periodically re-fetches cache entries that are about to expire
+// and evicts idle ones.
+func (a *transparencyProcessor) refreshLoop() {
+	defer a.wg.Done()
+	ticker := time.NewTicker(a.refreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-a.done:
+			return
+		case <-ticker.C:
+			for _, k := range a.attributesCache.expiring(a.refreshAhead) {
+				select {
+				case <-a.done:
+					return
+				default:
+				}
+				if host, path, ok := a.attributesCache.source(k); ok {
+					a.enqueueFetch(host, path)
+				}
+			}
+		}
+	}
+}
+
+// prefetchAll fetches the documents of all hosts in the service map with up
+// to workers concurrent fetches and marks the processor as ready afterwards.
+// Keys that are already cached and fresh, e.g. from a snapshot, are skipped.
+func (a *transparencyProcessor) prefetchAll() {
+	defer a.wg.Done()
+	defer atomic.StoreInt32(&a.ready, 1)
+
+	paths := append([]string{""}, a.prefetch.Paths...)
+	sem := make(chan struct{}, a.workers)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for host := range a.serviceMap {
+		for _, p := range paths {
+			if _, ok, stale := a.attributesCache.get(a.cacheKey(host, p)); ok && !stale {
+				continue
+			}
+			select {
+			case <-a.done:
+				return
+			case sem <- struct{}{}:
+			}
+			wg.Add(1)
+			go func(host, p string) {
+				defer wg.Done()
+				defer func() { <-sem }()
+				if _, err := a.updateAttributes(a.exportCtx, host, p); err != nil && a.exportCtx.Err() == nil {
+					a.logger.Warn("error prefetching tiltAttributes", zap.String("key", attributeKey(host, p)), zap.Error(err))
+				}
+			}(host, p)
+		}
+	}
+}
+
+// isReady reports whether the prefetch finished.
+func (a *transparencyProcessor) isReady() bool {
+	return atomic.LoadInt32(&a.ready) == 1
+}
+
+func (a *transparencyProcessor) handleReady(w http.ResponseWriter, _ *http.Request) {
+	if !a.isReady() {
+		http.Error(w, "prefetching TILT documents", http.StatusServiceUnavailable)
+		return
+	}
+	_, _ = w.Write([]byte("ready"))
+}
+
+// snapshotLoop periodically writes the cache to the snapshot file.
+func (a *transparencyProcessor) snapshotLoop() {
+	defer a.wg.Done()
+	ticker := time.NewTicker(a.snapshot.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-a.done:
+			return
+		case <-ticker.C:
+			if err := writeSnapshot(a.snapshot.Path, a.attributesCache); err != nil {
+				a.logger.Warn("error writing cache snapshot", zap.Error(err))
+			}
+		}
+	}
+}
+
+// fetchLoop fetches queued attributes until the processor is shut down.
+func (a *transparencyProcessor) fetchLoop() {
+	defer a.wg.Done()
+	for {
+		select {
+		case <-a.done:
+			return
+		case r := <-a.fetchQueue.queue:
+			start := time.Now()
+			_, err := a.updateAttributes(a.exportCtx, r.host, r.path)
+			a.fetchQueue.done(r.key)
+			if a.exportCtx.Err() != nil {
+				// Shutting down, the request was cancelled.
+				return
+			}
+			a.record(configtelemetry.LevelNormal,
+				statFetchLatency.M(float64(time.Since(start))/float64(time.Millisecond)),
+				statCacheSize.M(int64(a.attributesCache.len())))
+			if err != nil {
+				a.recordFetchError(r.host, err)
+				a.logger.Warn(fmt.Sprintf("error updating tiltAttributes: %v", err))
+			}
+		}
+	}
+}
+
+// enqueueFetch hands a fetch for host and path to the workers.
+func (a *transparencyProcessor) enqueueFetch(host, path string) {
+	key := a.cacheKey(host, path)
+	if !a.fetchQueue.enqueue(key, host, path) {
+		a.logger.Debug("fetch queue is full, dropping request", zap.String("key", key))
+	}
 }
 
 func (a *transparencyProcessor) processTraces(ctx context.Context, td ptrace.Traces) (ptrace.Traces, error) {
+	var enriched, skipped, redacted, hits, misses int64
+	violations := make(map[string]int64)
+	undeclared := make(map[string]int64)
+	defer func() {
+		a.record(configtelemetry.LevelBasic, statSpansEnriched.M(enriched), statSpansSkipped.M(skipped), statSpansRedacted.M(redacted))
+		a.record(configtelemetry.LevelNormal, statCacheHits.M(hits), statCacheMisses.M(misses))
+		a.recordViolations(violations)
+		a.recordTransfers(undeclared)
+	}()
+
 	rss := td.ResourceSpans()
 	for i := 0; i < rss.Len(); i++ {
 		rs := rss.At(i)
@@ -83,53 +401,280 @@ func (a *transparencyProcessor) processTraces(ctx context.Context, td ptrace.Tra
 			for k := 0; k < spans.Len(); k++ {
 				span := spans.At(k)
 				if filterspan.SkipSpan(a.include, a.exclude, span, resource, library) {
+					skipped++
 					continue
 				}
 
-				// Overwrite "linkerd-proxy" to the actual component name
-				component, ok := resource.Attributes().Get("linkerd.io/proxy-deployment")
+				// Overwrite e.g. "linkerd-proxy" to the actual component name
+				serviceName, ok := identifyProxy(a.meshes, resource)
 				if !ok {
+					if redactSpan(a.redactions, tiltAttributes{}, span.Attributes()) {
+						redacted++
+					}
 					continue
 				}
-				p := pcommon.NewValueString(fmt.Sprintf("%s-proxy", component.AsString()))
-				resource.Attributes().Update(conventions.AttributeServiceName, p)
+				if serviceName != "" {
+					resource.Attributes().UpdateString(conventions.AttributeServiceName, serviceName)
+				}
 
-				tHost, ok := span.Attributes().Get(conventions.AttributeHTTPHost)
+				tHost, tPath, ok := extractor.HostPath(a.extractors, extractor.Span{
+					Name:     span.Name(),
+					Attrs:    span.Attributes(),
+					Resource: resource.Attributes(),
+				})
 				if !ok {
-					tHost, ok = resource.Attributes().Get(conventions.AttributeHTTPHost)
-					if !ok {
-						continue
+					if redactSpan(a.redactions, tiltAttributes{}, span.Attributes()) {
+						redacted++
 					}
+					continue
 				}
 
-				k := attributeKey(tHost.AsString(), span.Name())
-				a.mu.RLock()
-				attr, ok := a.attributesCache[k]
-				a.mu.RUnlock()
+				k := a.cacheKey(tHost, tPath)
+				a.spanLinks.add(span.TraceID(), span.SpanID(), k)
+				attr, ok := a.cached(k, tHost, tPath)
 				if !ok {
-					a.logger.Info("no tiltAttributes found in cache for key", zap.String("key", k))
-					attributes, err := a.updateAttributes(tHost.AsString(), span.Name())
-					if err != nil {
-						a.logger.Warn(fmt.Sprintf("error updating tiltAttributes: %v", err))
+					// The span goes through un-enriched, later batches pick up the result.
+					misses++
+					a.logger.Debug("no tiltAttributes found in cache for key", zap.String("key", k))
+					if redactSpan(a.redactions, attr, span.Attributes()) {
+						redacted++
+					}
+					continue
+				}
+
+				hits++
+				a.enrichSpan(span, attr)
+				enriched++
+				if redactSpan(a.redactions, attr, span.Attributes()) {
+					redacted++
+				}
+				if a.graph != nil {
+					if source, ok := resource.Attributes().Get(conventions.AttributeServiceName); ok {
+						a.graph.add(source.AsString(), tHost, attr)
 					}
-					attr = attributes
 				}
 
-				insertAttributes(span, attrCategories, attr.categories)
-				insertAttributes(span, attrLegalBases, attr.legalBases)
-				insertAttributes(span, attrStorages, attr.storages)
-				insertAttributes(span, attrPurposes, attr.puproses)
-				if attr.automatedDecision {
-					span.Attributes().InsertBool(attrAutomatedDecision, attr.automatedDecision)
+				if v := evaluatePolicies(a.policies, attr); len(v) > 0 {
+					insertAttributes(span.Attributes(), attrViolations, v)
+					for _, name := range v {
+						violations[name]++
+					}
+				}
+
+				if country, ok := a.transfers.country(tHost, span.Attributes(), resource.Attributes()); ok {
+					if _, inEEA := eea[country]; !inEEA {
+						span.Attributes().InsertString(attrTransferCountry, country)
+					}
+					if undeclaredTransfer(country, attr) {
+						span.Attributes().InsertBool(attrTransferUndeclared, true)
+						undeclared[country]++
+					}
 				}
-				span.Attributes().InsertString(attrLegitimateInterests, fmt.Sprintf("%v", attr.legitametInterests))
 			}
 		}
 	}
 	return td, nil
 }
 
-func insertAttributes(span ptrace.Span, key string, values []string) {
+// enrichSpan adds attr to span in the configured output mode.
+func (a *transparencyProcessor) enrichSpan(span ptrace.Span, attr tiltAttributes) {
+	if a.output != OutputEvents {
+		a.enrichAttributes(span.Attributes(), attr, span.StartTimestamp())
+		return
+	}
+	for _, d := range attr.dataDisclosed {
+		ev := span.Events().AppendEmpty()
+		ev.SetName(attrDataDisclosed)
+		ev.SetTimestamp(span.StartTimestamp())
+		ev.Attributes().InsertString(attrCategory, d.category)
+		insertAttributes(ev.Attributes(), attrLegalBases, d.legalBases)
+		insertAttributes(ev.Attributes(), attrCanonicalLegalBases, d.canonicalLegalBases)
+		insertAttributes(ev.Attributes(), attrStorages, d.storages)
+		insertAttributes(ev.Attributes(), attrPurposes, d.purposes)
+		ev.Attributes().InsertString(attrLegitimateInterests, fmt.Sprintf("%v", d.legitimateInterests))
+	}
+	a.enrichCommon(span.Attributes(), attr, span.StartTimestamp())
+}
+
+// enrichAttributes adds attr to attrs of a span or log record observed at ts
+// in the configured output mode. Events are not supported here, they fall
+// back to the indexed attributes.
+func (a *transparencyProcessor) enrichAttributes(attrs pcommon.Map, attr tiltAttributes, ts pcommon.Timestamp) {
+	switch a.output {
+	case OutputIndexed, OutputEvents:
+		for i, d := range attr.dataDisclosed {
+			prefix := fmt.Sprintf("%s.%d.", attrDataDisclosed, i)
+			attrs.InsertString(prefix+"category", d.category)
+			insertAttributes(attrs, prefix+"legal_bases", d.legalBases)
+			insertAttributes(attrs, prefix+"legal_bases_canonical", d.canonicalLegalBases)
+			insertAttributes(attrs, prefix+"storage_durations", d.storages)
+			insertAttributes(attrs, prefix+"purposes", d.purposes)
+			attrs.InsertString(prefix+"legitimate_interests", fmt.Sprintf("%v", d.legitimateInterests))
+		}
+	default:
+		insertAttributes(attrs, attrCategories, attr.categories)
+		insertAttributes(attrs, attrLegalBases, attr.legalBases)
+		insertAttributes(attrs, attrCanonicalLegalBases, attr.canonicalLegalBases)
+		insertAttributes(attrs, attrStorages, attr.storages)
+		insertAttributes(attrs, attrPurposes, attr.puproses)
+		attrs.InsertString(attrLegitimateInterests, fmt.Sprintf("%v", attr.legitametInterests))
+	}
+	a.enrichCommon(attrs, attr, ts)
+}
+
+// enrichCommon adds the attributes that do not depend on the output mode. The
+// retention deadline is counted from ts and omitted if ts is not set.
+func (a *transparencyProcessor) enrichCommon(attrs pcommon.Map, attr tiltAttributes, ts pcommon.Timestamp) {
+	insertAttributes(attrs, attrValidationErrors, attr.validationErrors)
+	if attr.automatedDecision {
+		attrs.InsertBool(attrAutomatedDecision, attr.automatedDecision)
+	}
+	if attr.maxStorage > 0 {
+		attrs.InsertInt(attrMaxStorage, int64(attr.maxStorage/time.Second))
+		if ts != 0 {
+			attrs.InsertString(attrRetentionUntil, ts.AsTime().Add(attr.maxStorage).UTC().Format(time.RFC3339))
+		}
+	}
+}
+
+func (a *transparencyProcessor) processLogs(ctx context.Context, ld plog.Logs) (plog.Logs, error) {
+	rls := ld.ResourceLogs()
+	for i := 0; i < rls.Len(); i++ {
+		rl := rls.At(i)
+		resource := rl.Resource()
+		slls := rl.ScopeLogs()
+		for j := 0; j < slls.Len(); j++ {
+			sl := slls.At(j)
+			lrs := sl.LogRecords()
+			library := sl.Scope()
+			for k := 0; k < lrs.Len(); k++ {
+				lr := lrs.At(k)
+				if filterlog.SkipLogRecord(a.logInclude, a.logExclude, lr, resource, library) {
+					continue
+				}
+
+				if attr, ok := a.logAttributes(lr, resource); ok {
+					ts := lr.Timestamp()
+					if ts == 0 {
+						ts = lr.ObservedTimestamp()
+					}
+					a.enrichAttributes(lr.Attributes(), attr, ts)
+				}
+			}
+		}
+	}
+	return ld, nil
+}
+
+// logAttributes looks up the attributes for a log record. Records with an
+// http.host are keyed by host and http.target or http.route, other records
+// by the span they were emitted for.
+func (a *transparencyProcessor) logAttributes(lr plog.LogRecord, resource pcommon.Resource) (tiltAttributes, bool) {
+	var k, tHost, tPath string
+	if h, ok := logAttribute(lr, resource, conventions.AttributeHTTPHost); ok {
+		tHost = h
+		tPath, ok = logAttribute(lr, resource, conventions.AttributeHTTPTarget)
+		if !ok {
+			tPath, _ = logAttribute(lr, resource, conventions.AttributeHTTPRoute)
+		}
+		tPath, _, _ = strings.Cut(tPath, "?")
+		k = a.cacheKey(tHost, tPath)
+	} else if linked, ok := a.spanLinks.get(lr.TraceID(), lr.SpanID()); ok {
+		k = linked
+		tHost, tPath, _ = a.attributesCache.source(k)
+	} else {
+		return tiltAttributes{}, false
+	}
+
+	if tHost == "" {
+		attr, ok, _ := a.attributesCache.get(k)
+		return attr, ok && attr.resolved
+	}
+	return a.cached(k, tHost, tPath)
+}
+
+// cached returns the attributes cached for key k of httpHost and httpPath and
+// enqueues a fetch if they are missing or stale. Stale attributes are served
+// while the refresh runs. Until a document is resolved for the path, the
+// host-wide document cached for the empty path, e.g. by the prefetch, is used.
+func (a *transparencyProcessor) cached(k, httpHost, httpPath string) (tiltAttributes, bool) {
+	attr, ok, stale := a.attributesCache.get(k)
+	if !ok || stale {
+		a.enqueueFetch(httpHost, httpPath)
+	}
+	if ok && attr.resolved {
+		return attr, true
+	}
+	if root := a.cacheKey(httpHost, ""); root != k {
+		attr, ok, _ = a.attributesCache.get(root)
+	}
+	return attr, ok && attr.resolved
+}
+
+func (a *transparencyProcessor) processMetrics(ctx context.Context, md pmetric.Metrics) (pmetric.Metrics, error) {
+	rms := md.ResourceMetrics()
+	for i := 0; i < rms.Len(); i++ {
+		rm := rms.At(i)
+		resource := rm.Resource()
+		if !a.matchesAnyMetric(rm) {
+			continue
+		}
+
+		service, ok := resource.Attributes().Get(conventions.AttributeServiceName)
+		if !ok {
+			continue
+		}
+
+		k := a.cacheKey(service.AsString(), "")
+		attr, ok, stale := a.attributesCache.get(k)
+		if !ok || stale {
+			a.enqueueFetch(service.AsString(), "")
+		}
+		if !ok || !attr.resolved {
+			continue
+		}
+
+		// Metrics are aggregated per resource, so only the document-wide lists are added.
+		attrs := resource.Attributes()
+		insertAttributes(attrs, attrCategories, attr.categories)
+		insertAttributes(attrs, attrPurposes, attr.puproses)
+		insertAttributes(attrs, attrLegalBases, attr.legalBases)
+		insertAttributes(attrs, attrCanonicalLegalBases, attr.canonicalLegalBases)
+	}
+	return md, nil
+}
+
+// matchesAnyMetric reports whether at least one metric of rm passes the
+// include and exclude properties.
+func (a *transparencyProcessor) matchesAnyMetric(rm pmetric.ResourceMetrics) bool {
+	resource := rm.Resource()
+	smss := rm.ScopeMetrics()
+	for i := 0; i < smss.Len(); i++ {
+		sm := smss.At(i)
+		metrics := sm.Metrics()
+		for j := 0; j < metrics.Len(); j++ {
+			if !filtermetric.SkipMetric(a.metricInclude, a.metricExclude, metrics.At(j), resource, sm.Scope()) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// logAttribute returns the string value of key from the log record, falling
+// back to its resource.
+func logAttribute(lr plog.LogRecord, resource pcommon.Resource, key string) (string, bool) {
+	v, ok := lr.Attributes().Get(key)
+	if !ok {
+		v, ok = resource.Attributes().Get(key)
+	}
+	if !ok {
+		return "", false
+	}
+	return v.AsString(), true
+}
+
+func insertAttributes(attrs pcommon.Map, key string, values []string) {
 	if len(values) == 0 {
 		return
 	}
@@ -141,64 +686,127 @@ func insertAttributes(span ptrace.Span, key string, values []string) {
 	}
 	vs := pcommon.NewValueSlice()
 	b.CopyTo(vs.SliceVal())
-	span.Attributes().Insert(key, vs)
+	attrs.Insert(key, vs)
+}
+
+// cacheKey returns the key of the attributes for host and httpPath, with the
+// path normalized to its route template.
+func (a *transparencyProcessor) cacheKey(host, httpPath string) string {
+	return attributeKey(host, a.routes.normalize(host, httpPath))
 }
 
 func attributeKey(httHost, httpPath string) string {
 	return path.Clean(fmt.Sprintf("%s/%s", httHost, httpPath))
 }
 
-func (a *transparencyProcessor) updateAttributes(httpHost, httpPath string) (tiltAttributes, error) {
-	host, ok := a.serviceMap[httpHost]
-	if !ok {
-		host = httpHost
+// updateAttributes resolves the attributes for httpHost and httpPath from the
+// sources and stores them in the cache. If resolving fails, the failure is
+// recorded in the cache so that the key is retried with backoff instead of on
+// every span. A request cancelled through ctx is not recorded as failure.
+func (a *transparencyProcessor) updateAttributes(ctx context.Context, httpHost, httpPath string) (tiltAttributes, error) {
+	doc, err := a.sources.Resolve(ctx, httpHost, httpPath)
+	if err != nil && ctx.Err() != nil {
+		return tiltAttributes{}, err
+	}
+	if err == nil {
+		a.routes.learn(httpHost, doc.Routes)
+	}
+	key := a.cacheKey(httpHost, httpPath)
+	if raw := attributeKey(httpHost, httpPath); raw != key {
+		// The entry cached before the route templates were known is replaced by the template's.
+		a.attributesCache.remove(raw)
 	}
-	if !strings.Contains(httpPath, "tilt/") {
-		httpPath = "tilt/" + httpPath
+	var validationErrors []string
+	if err == nil {
+		validationErrors, err = a.validate(key, doc)
 	}
-	u := url.URL{
-		Scheme: "http",
-		Host:   host,
-		Path:   path.Clean(httpPath),
+	if err != nil {
+		a.attributesCache.fail(key, httpHost, httpPath)
+		return tiltAttributes{}, err
 	}
+	attributes := newTiltAttributes(doc)
+	attributes.validationErrors = validationErrors
+	var unparsed []string
+	for _, l := range attributes.unparsedLegalBases {
+		if _, seen := a.unparsedLegalBases.LoadOrStore(l, struct{}{}); !seen {
+			unparsed = append(unparsed, l)
+		}
+	}
+	if n := len(unparsed); n > 0 {
+		a.logger.Debug("unparsed legal basis references", zap.String("key", key), zap.Strings("references", unparsed))
+		a.record(configtelemetry.LevelBasic, statUnparsedLegalBases.M(int64(n)))
+	}
+	a.attributesCache.set(key, httpHost, httpPath, attributes)
+	return attributes, nil
+}
 
-	res, err := http.Get(u.String())
-	if err != nil || res.StatusCode >= 400 {
-		a.mu.Lock()
-		a.attributesCache[attributeKey(httpHost, httpPath)] = tiltAttributes{lastUpdated: time.Now()}
-		a.mu.Unlock()
-		return tiltAttributes{}, fmt.Errorf("error fetching spec from %q: %v", u.String(), err)
+// validate checks doc against the TILT schema. Depending on the validation
+// mode, violations are returned as error, returned for enrichment or logged.
+func (a *transparencyProcessor) validate(key string, doc *tilt.Document) ([]string, error) {
+	if len(doc.Raw) == 0 {
+		return nil, nil
 	}
-	defer res.Body.Close()
-	d := json.NewDecoder(res.Body)
-	spec := new(tiltSpec)
-	if err := d.Decode(spec); err != nil {
-		return tiltAttributes{}, fmt.Errorf("error decoding spec from %q: %v", u.String(), err)
+	err := tilt.Validate(doc.Raw)
+	if err == nil {
+		return nil, nil
 	}
+	a.record(configtelemetry.LevelBasic, statInvalidDocuments.M(1))
 
-	attributes := tiltAttributes{}
+	switch a.validation {
+	case ValidationReject:
+		return nil, fmt.Errorf("rejected document for %q: %w", key, err)
+	case ValidationEnrich:
+		var ve *tilt.ValidationError
+		if errors.As(err, &ve) {
+			return ve.Errors, nil
+		}
+		return []string{err.Error()}, nil
+	default:
+		a.logger.Warn("invalid TILT document", zap.String("key", key), zap.Error(err))
+		return nil, nil
+	}
+}
+
+// newTiltAttributes flattens a TILT document into the attributes added to spans.
+func newTiltAttributes(spec *tilt.Document) tiltAttributes {
+	attributes := tiltAttributes{resolved: true, document: spec.Raw}
+	for _, t := range spec.ThirdCountryTransfers {
+		attributes.thirdCountries = append(attributes.thirdCountries, countryCode(t.Country))
+	}
 
 	for _, d := range spec.DataDisclosed {
-		attributes.categories = append(attributes.categories, d.Category)
+		disclosed := disclosedAttributes{category: d.Category}
 		for _, l := range d.LegalBases {
-			attributes.legalBases = append(attributes.legalBases, l.Reference)
+			disclosed.legalBases = append(disclosed.legalBases, l.Reference)
+			canonical, ok := tilt.NormalizeLegalBasis(l.Reference)
+			if !ok {
+				attributes.unparsedLegalBases = append(attributes.unparsedLegalBases, l.Reference)
+			}
+			disclosed.canonicalLegalBases = append(disclosed.canonicalLegalBases, canonical)
 		}
 		for _, p := range d.Purposes {
-			attributes.puproses = append(attributes.puproses, p.Purpose)
+			disclosed.purposes = append(disclosed.purposes, p.Purpose)
 		}
 		for _, l := range d.LegitimateInterests {
-			attributes.legitametInterests = append(attributes.legitametInterests, l.Exists)
+			disclosed.legitimateInterests = append(disclosed.legitimateInterests, l.Exists)
 		}
 		for _, s := range d.Storage {
 			for _, t := range s.Temporal {
-				attributes.storages = append(attributes.storages, t.TTL)
+				disclosed.storages = append(disclosed.storages, t.TTL)
+				if d, ok := tilt.ParseTTL(t.TTL); ok && d > attributes.maxStorage {
+					attributes.maxStorage = d
+				}
 			}
 		}
+		attributes.dataDisclosed = append(attributes.dataDisclosed, disclosed)
+
+		attributes.categories = append(attributes.categories, disclosed.category)
+		attributes.legalBases = append(attributes.legalBases, disclosed.legalBases...)
+		attributes.canonicalLegalBases = append(attributes.canonicalLegalBases, disclosed.canonicalLegalBases...)
+		attributes.puproses = append(attributes.puproses, disclosed.purposes...)
+		attributes.legitametInterests = append(attributes.legitametInterests, disclosed.legitimateInterests...)
+		attributes.storages = append(attributes.storages, disclosed.storages...)
 		attributes.automatedDecision = spec.AutomatedDecisionMaking.InUse
 	}
-
-	a.mu.Lock()
-	a.attributesCache[attributeKey(httpHost, httpPath)] = attributes
-	a.mu.Unlock()
-	return attributes, nil
+	return attributes
 }
@@ -16,7 +16,11 @@ package transparencyprocessor
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -24,10 +28,14 @@ import (
 	"go.opentelemetry.io/collector/component/componenttest"
 	"go.opentelemetry.io/collector/consumer/consumertest"
 	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
 	"go.opentelemetry.io/collector/pdata/ptrace"
 	conventions "go.opentelemetry.io/collector/semconv/v1.6.1"
 
+	"github.com/mindtastic/opentelemetry-transparency-processor/internal/filterconfig"
 	"github.com/mindtastic/opentelemetry-transparency-processor/internal/filterset"
+	"github.com/mindtastic/opentelemetry-transparency-processor/tilt"
 )
 
 // Common structure for all the Tests
@@ -35,24 +43,33 @@ type testCase struct {
 	skip               bool
 	name               string
 	serviceName        string
+	resourceAttributes map[string]interface{}
 	inputAttributes    map[string]interface{}
 	expectedAttributes map[string]interface{}
 }
 
 // runIndividualTestCase is the common logic of passing trace data through a configured tiltAttributes processor.
+// TILT documents are fetched in the background, so the trace data is passed until the expected attributes show up.
 func runIndividualTestCase(t *testing.T, tt testCase, tp component.TracesProcessor) {
 	t.Run(tt.name, func(t *testing.T) {
-		td := generateTraceData(tt.serviceName, tt.name, tt.inputAttributes)
-		assert.NoError(t, tp.ConsumeTraces(context.Background(), td))
-		// Ensure that the modified `td` has the tiltAttributes sorted:
-		sortAttributes(td)
-		require.Equal(t, generateTraceData(tt.serviceName, tt.name, tt.expectedAttributes), td)
+		expected := generateTraceData(tt.serviceName, tt.name, tt.resourceAttributes, tt.expectedAttributes)
+		sortAttributes(expected)
+		var td ptrace.Traces
+		assert.Eventually(t, func() bool {
+			td = generateTraceData(tt.serviceName, tt.name, tt.resourceAttributes, tt.inputAttributes)
+			assert.NoError(t, tp.ConsumeTraces(context.Background(), td))
+			// Ensure that the modified `td` has the tiltAttributes sorted:
+			sortAttributes(td)
+			return assert.ObjectsAreEqual(expected, td)
+		}, time.Second, 10*time.Millisecond)
+		require.Equal(t, expected, td)
 	})
 }
 
-func generateTraceData(serviceName, spanName string, attrs map[string]interface{}) ptrace.Traces {
+func generateTraceData(serviceName, spanName string, resourceAttrs, attrs map[string]interface{}) ptrace.Traces {
 	td := ptrace.NewTraces()
 	rs := td.ResourceSpans().AppendEmpty()
+	pcommon.NewMapFromRaw(resourceAttrs).CopyTo(rs.Resource().Attributes())
 	if serviceName != "" {
 		rs.Resource().Attributes().UpsertString(conventions.AttributeServiceName, serviceName)
 	}
@@ -78,6 +95,40 @@ func sortAttributes(td ptrace.Traces) {
 	}
 }
 
+const testTiltDocument = `{
+	"dataDisclosed": [{
+		"category": "testing",
+		"purposes": [{"purpose": "testing purposes"}],
+		"legalBases": [{"reference": "GDPR-6-1-a"}],
+		"legitimateInterests": [{"exists": false}]
+	}]
+}`
+
+// testEnrichedAttributes returns attrs together with the attributes
+// testTiltDocument adds to a span in the flat output mode.
+func testEnrichedAttributes(attrs map[string]interface{}) map[string]interface{} {
+	enriched := map[string]interface{}{
+		"tilt.categories":            []interface{}{"testing"},
+		"tilt.legal_bases":           []interface{}{"GDPR-6-1-a"},
+		"tilt.legal_bases_canonical": []interface{}{"GDPR-6-1-a"},
+		"tilt.purposes":              []interface{}{"testing purposes"},
+		"tilt.legitimate_interests":  "[false]",
+	}
+	for k, v := range attrs {
+		enriched[k] = v
+	}
+	return enriched
+}
+
+// newTiltServer starts a server that answers every /tilt/ request with doc.
+func newTiltServer(t testing.TB, doc string) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(doc))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
 func TestProcessTraces(t *testing.T) {
 	testCases := []testCase{
 		{
@@ -98,23 +149,28 @@ func TestProcessTraces(t *testing.T) {
 			skip:        false,
 			name:        "add tiltAttributes",
 			serviceName: "linkerd-proxy",
+			resourceAttributes: map[string]interface{}{
+				"linkerd.io/proxy-deployment": "linkerd",
+			},
 			inputAttributes: map[string]interface{}{
 				"http.host": "testHost",
 				"http.path": "testPath",
 			},
-			expectedAttributes: map[string]interface{}{
-				"http.host":       "testHost",
-				"http.path":       "testPath",
-				"tilt.categories": "testing",
-			},
+			expectedAttributes: testEnrichedAttributes(map[string]interface{}{
+				"http.host": "testHost",
+				"http.path": "testPath",
+			}),
 		},
 	}
 
+	srv := newTiltServer(t, testTiltDocument)
 	factory := NewFactory()
 	cfg := factory.CreateDefaultConfig()
+	cfg.(*Config).ServiceMap = map[string]string{"testHost": srv.Listener.Addr().String()}
 	tp, err := factory.CreateTracesProcessor(context.Background(), componenttest.NewNopProcessorCreateSettings(), cfg, consumertest.NewNop())
 	require.Nil(t, err)
 	require.NotNil(t, tp)
+	tp = startedProcessor(t, tp)
 
 	for _, tt := range testCases {
 		if tt.skip {
@@ -124,6 +180,345 @@ func TestProcessTraces(t *testing.T) {
 	}
 }
 
+func TestProcessTracesFetchesInBackground(t *testing.T) {
+	var requests int32
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		<-release
+		_, _ = w.Write([]byte(testTiltDocument))
+	}))
+	defer srv.Close()
+
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.ServiceMap = map[string]string{"testHost": srv.Listener.Addr().String()}
+	tp, err := factory.CreateTracesProcessor(context.Background(), componenttest.NewNopProcessorCreateSettings(), cfg, consumertest.NewNop())
+	require.NoError(t, err)
+	tp = startedProcessor(t, tp)
+
+	resourceAttrs := map[string]interface{}{"linkerd.io/proxy-deployment": "linkerd"}
+	attrs := map[string]interface{}{"http.host": "testHost"}
+	for i := 0; i < 5; i++ {
+		td := generateTraceData("linkerd-proxy", "/path", resourceAttrs, attrs)
+		require.NoError(t, tp.ConsumeTraces(context.Background(), td))
+		sortAttributes(td)
+		assert.Equal(t, generateTraceData("linkerd-proxy", "/path", resourceAttrs, attrs), td, "batch goes through un-enriched")
+	}
+	close(release)
+
+	assert.Eventually(t, func() bool {
+		td := generateTraceData("linkerd-proxy", "/path", resourceAttrs, attrs)
+		require.NoError(t, tp.ConsumeTraces(context.Background(), td))
+		_, ok := td.ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0).Attributes().Get(attrCategories)
+		return ok
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests), "concurrent misses are collapsed")
+}
+
+func TestShutdownCancelsFetches(t *testing.T) {
+	received := make(chan struct{})
+	cancelled := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(received)
+		<-r.Context().Done()
+		close(cancelled)
+	}))
+	defer srv.Close()
+
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.ServiceMap = map[string]string{"testHost": srv.Listener.Addr().String()}
+	cfg.Client.Timeout = time.Minute
+	tp, err := factory.CreateTracesProcessor(context.Background(), componenttest.NewNopProcessorCreateSettings(), cfg, consumertest.NewNop())
+	require.NoError(t, err)
+	require.NoError(t, tp.Start(context.Background(), componenttest.NewNopHost()))
+
+	td := generateTraceData("linkerd-proxy", "/path", map[string]interface{}{"linkerd.io/proxy-deployment": "linkerd"}, map[string]interface{}{"http.host": "testHost"})
+	require.NoError(t, tp.ConsumeTraces(context.Background(), td))
+	<-received
+
+	start := time.Now()
+	require.NoError(t, tp.Shutdown(context.Background()))
+	assert.Less(t, time.Since(start), 5*time.Second)
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("in-flight request was not cancelled")
+	}
+}
+
+func TestProcessTracesClientSettings(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(testTiltDocument))
+	}))
+	defer srv.Close()
+
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.ServiceMap = map[string]string{"testHost": srv.Listener.Addr().String()}
+	cfg.Client.Scheme = "https"
+	cfg.Client.TLSSetting.InsecureSkipVerify = true
+	cfg.Client.Headers = map[string]string{"Authorization": "Bearer token"}
+	require.NoError(t, cfg.Validate())
+	tp, err := factory.CreateTracesProcessor(context.Background(), componenttest.NewNopProcessorCreateSettings(), cfg, consumertest.NewNop())
+	require.NoError(t, err)
+
+	runIndividualTestCase(t, testCase{
+		name:               "https",
+		serviceName:        "linkerd-proxy",
+		resourceAttributes: map[string]interface{}{"linkerd.io/proxy-deployment": "linkerd"},
+		inputAttributes:    map[string]interface{}{"http.host": "testHost"},
+		expectedAttributes: testEnrichedAttributes(map[string]interface{}{
+			"http.host": "testHost",
+		}),
+	}, startedProcessor(t, tp))
+}
+
+// startedProcessor starts tp and shuts it down when the test finished.
+func startedProcessor(t *testing.T, tp component.TracesProcessor) component.TracesProcessor {
+	require.NoError(t, tp.Start(context.Background(), componenttest.NewNopHost()))
+	t.Cleanup(func() { require.NoError(t, tp.Shutdown(context.Background())) })
+	return tp
+}
+
+func TestProcessTracesFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "testHost.json", testTiltDocument)
+
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.Sources = []SourceConfig{{Type: sourceTypeFiles, FilesConfig: FilesConfig{Directory: dir}}}
+	tp, err := factory.CreateTracesProcessor(context.Background(), componenttest.NewNopProcessorCreateSettings(), cfg, consumertest.NewNop())
+	require.NoError(t, err)
+	tp = startedProcessor(t, tp)
+
+	tt := testCase{
+		name:               "files",
+		serviceName:        "linkerd-proxy",
+		resourceAttributes: map[string]interface{}{"linkerd.io/proxy-deployment": "linkerd"},
+		inputAttributes:    map[string]interface{}{"http.host": "testHost"},
+		expectedAttributes: testEnrichedAttributes(map[string]interface{}{
+			"http.host": "testHost",
+		}),
+	}
+	runIndividualTestCase(t, tt, tp)
+
+	writeFile(t, dir, "testHost.json", `{"dataDisclosed": [{"category": "reloaded"}]}`)
+	tt.expectedAttributes = map[string]interface{}{
+		"http.host":                 "testHost",
+		"tilt.categories":           []interface{}{"reloaded"},
+		"tilt.legitimate_interests": "[]",
+	}
+	runIndividualTestCase(t, tt, tp)
+}
+
+func TestEnrichSpanOutput(t *testing.T) {
+	attr := tiltAttributes{
+		categories: []string{"email", "health"},
+		puproses:   []string{"newsletter", "therapy"},
+		dataDisclosed: []disclosedAttributes{
+			{category: "email", purposes: []string{"newsletter"}, legalBases: []string{"Art. 6(1)(a) GDPR"}, canonicalLegalBases: []string{"GDPR-6-1-a"}},
+			{category: "health", purposes: []string{"therapy"}, legalBases: []string{"9.2.a"}, canonicalLegalBases: []string{"GDPR-9-2-a"}},
+		},
+	}
+
+	tp := &transparencyProcessor{output: OutputIndexed}
+	span := ptrace.NewSpan()
+	tp.enrichSpan(span, attr)
+	span.Attributes().Sort()
+	assert.Equal(t, pcommon.NewMapFromRaw(map[string]interface{}{
+		"tilt.data_disclosed.0.category":              "email",
+		"tilt.data_disclosed.0.purposes":              []interface{}{"newsletter"},
+		"tilt.data_disclosed.0.legal_bases":           []interface{}{"Art. 6(1)(a) GDPR"},
+		"tilt.data_disclosed.0.legal_bases_canonical": []interface{}{"GDPR-6-1-a"},
+		"tilt.data_disclosed.0.legitimate_interests":  "[]",
+		"tilt.data_disclosed.1.category":              "health",
+		"tilt.data_disclosed.1.purposes":              []interface{}{"therapy"},
+		"tilt.data_disclosed.1.legal_bases":           []interface{}{"9.2.a"},
+		"tilt.data_disclosed.1.legal_bases_canonical": []interface{}{"GDPR-9-2-a"},
+		"tilt.data_disclosed.1.legitimate_interests":  "[]",
+	}).Sort(), span.Attributes())
+
+	tp = &transparencyProcessor{output: OutputEvents}
+	span = ptrace.NewSpan()
+	span.SetStartTimestamp(pcommon.NewTimestampFromTime(time.Unix(1600000000, 0)))
+	tp.enrichSpan(span, attr)
+	assert.Equal(t, 0, span.Attributes().Len())
+	require.Equal(t, 2, span.Events().Len())
+	for i, d := range attr.dataDisclosed {
+		ev := span.Events().At(i)
+		assert.Equal(t, "tilt.data_disclosed", ev.Name())
+		assert.Equal(t, span.StartTimestamp(), ev.Timestamp())
+		ev.Attributes().Sort()
+		assert.Equal(t, pcommon.NewMapFromRaw(map[string]interface{}{
+			"tilt.category":              d.category,
+			"tilt.purposes":              []interface{}{d.purposes[0]},
+			"tilt.legal_bases":           []interface{}{d.legalBases[0]},
+			"tilt.legal_bases_canonical": []interface{}{d.canonicalLegalBases[0]},
+			"tilt.legitimate_interests":  "[]",
+		}).Sort(), ev.Attributes())
+	}
+}
+
+func TestEnrichRetention(t *testing.T) {
+	doc, err := tilt.Unmarshal([]byte(`{"dataDisclosed": [
+		{"category": "email", "storage": [{"temporal": [{"ttl": "P30D"}, {"ttl": "until unsubscribed"}]}]},
+		{"category": "invoices", "storage": [{"temporal": [{"ttl": "10 years"}]}]}
+	]}`))
+	require.NoError(t, err)
+	attr := newTiltAttributes(doc)
+	assert.Equal(t, 3650*24*time.Hour, attr.maxStorage)
+
+	tp := &transparencyProcessor{}
+	span := ptrace.NewSpan()
+	span.SetStartTimestamp(pcommon.NewTimestampFromTime(time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)))
+	tp.enrichSpan(span, attr)
+	maxStorage, _ := span.Attributes().Get("tilt.storage.max_seconds")
+	assert.Equal(t, int64(3650*24*60*60), maxStorage.IntVal())
+	until, _ := span.Attributes().Get("tilt.retention_until")
+	assert.Equal(t, "2029-12-29T12:00:00Z", until.StringVal())
+
+	attrs := pcommon.NewMap()
+	tp.enrichAttributes(attrs, attr, 0)
+	_, ok := attrs.Get("tilt.retention_until")
+	assert.False(t, ok, "no deadline without timestamp")
+	_, ok = attrs.Get("tilt.storage.max_seconds")
+	assert.True(t, ok)
+}
+
+func generateLogData(traceID pcommon.TraceID, spanID pcommon.SpanID, attrs map[string]interface{}) plog.Logs {
+	ld := plog.NewLogs()
+	lr := ld.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty()
+	lr.SetTraceID(traceID)
+	lr.SetSpanID(spanID)
+	pcommon.NewMapFromRaw(attrs).CopyTo(lr.Attributes())
+	return ld
+}
+
+func TestProcessLogs(t *testing.T) {
+	srv := newTiltServer(t, testTiltDocument)
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.ServiceMap = map[string]string{"testHost": srv.Listener.Addr().String()}
+	tp, err := factory.CreateTracesProcessor(context.Background(), componenttest.NewNopProcessorCreateSettings(), cfg, consumertest.NewNop())
+	require.NoError(t, err)
+	lp, err := factory.CreateLogsProcessor(context.Background(), componenttest.NewNopProcessorCreateSettings(), cfg, consumertest.NewNop())
+	require.NoError(t, err)
+	require.NoError(t, lp.Start(context.Background(), componenttest.NewNopHost()))
+	tp = startedProcessor(t, tp)
+	t.Cleanup(func() { require.NoError(t, lp.Shutdown(context.Background())) })
+
+	enriched := func(ld plog.Logs) bool {
+		v, ok := ld.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0).Attributes().Get(attrCategories)
+		return ok && v.SliceVal().At(0).StringVal() == "testing"
+	}
+
+	t.Run("http attributes", func(t *testing.T) {
+		assert.Eventually(t, func() bool {
+			ld := generateLogData(pcommon.InvalidTraceID(), pcommon.InvalidSpanID(), map[string]interface{}{
+				"http.host":   "testHost",
+				"http.target": "/logs?page=1",
+			})
+			require.NoError(t, lp.ConsumeLogs(context.Background(), ld))
+			return enriched(ld)
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("span link", func(t *testing.T) {
+		traceID := pcommon.NewTraceID([16]byte{1, 2, 3})
+		spanID := pcommon.NewSpanID([8]byte{4, 5, 6})
+		assert.Eventually(t, func() bool {
+			td := generateTraceData("linkerd-proxy", "/linked", map[string]interface{}{"linkerd.io/proxy-deployment": "linkerd"}, map[string]interface{}{"http.host": "testHost"})
+			span := td.ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0)
+			span.SetTraceID(traceID)
+			span.SetSpanID(spanID)
+			require.NoError(t, tp.ConsumeTraces(context.Background(), td))
+
+			ld := generateLogData(traceID, spanID, nil)
+			require.NoError(t, lp.ConsumeLogs(context.Background(), ld))
+			return enriched(ld)
+		}, time.Second, 10*time.Millisecond)
+
+		ld := generateLogData(pcommon.NewTraceID([16]byte{9}), spanID, nil)
+		require.NoError(t, lp.ConsumeLogs(context.Background(), ld))
+		assert.False(t, enriched(ld), "records of unknown spans are not enriched")
+	})
+}
+
+func TestCreateLogsProcessorSpanProperties(t *testing.T) {
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.Include = &filterconfig.MatchProperties{
+		Config:   *createConfig(filterset.Strict),
+		Services: []string{"testing"},
+	}
+	_, err := factory.CreateLogsProcessor(context.Background(), componenttest.NewNopProcessorCreateSettings(), cfg, consumertest.NewNop())
+	assert.Error(t, err, "services cannot be matched for log records")
+}
+
+func generateMetricData(serviceName string, metricNames ...string) pmetric.Metrics {
+	md := pmetric.NewMetrics()
+	for _, name := range metricNames {
+		rm := md.ResourceMetrics().AppendEmpty()
+		rm.Resource().Attributes().InsertString(conventions.AttributeServiceName, serviceName)
+		m := rm.ScopeMetrics().AppendEmpty().Metrics().AppendEmpty()
+		m.SetName(name)
+		m.SetDataType(pmetric.MetricDataTypeSum)
+	}
+	return md
+}
+
+func TestProcessMetrics(t *testing.T) {
+	srv := newTiltServer(t, testTiltDocument)
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.ServiceMap = map[string]string{"testHost": srv.Listener.Addr().String()}
+	cfg.Include = &filterconfig.MatchProperties{
+		Config:      *createConfig(filterset.Strict),
+		MetricNames: []string{"response_total"},
+	}
+	mp, err := factory.CreateMetricsProcessor(context.Background(), componenttest.NewNopProcessorCreateSettings(), cfg, consumertest.NewNop())
+	require.NoError(t, err)
+	require.NoError(t, mp.Start(context.Background(), componenttest.NewNopHost()))
+	t.Cleanup(func() { require.NoError(t, mp.Shutdown(context.Background())) })
+
+	var md pmetric.Metrics
+	assert.Eventually(t, func() bool {
+		md = generateMetricData("testHost", "response_total", "process_cpu_seconds_total")
+		require.NoError(t, mp.ConsumeMetrics(context.Background(), md))
+		_, ok := md.ResourceMetrics().At(0).Resource().Attributes().Get(attrCategories)
+		return ok
+	}, time.Second, 10*time.Millisecond)
+
+	attrs := md.ResourceMetrics().At(0).Resource().Attributes()
+	attrs.Sort()
+	assert.Equal(t, pcommon.NewMapFromRaw(map[string]interface{}{
+		"service.name":               "testHost",
+		"tilt.categories":            []interface{}{"testing"},
+		"tilt.legal_bases":           []interface{}{"GDPR-6-1-a"},
+		"tilt.legal_bases_canonical": []interface{}{"GDPR-6-1-a"},
+		"tilt.purposes":              []interface{}{"testing purposes"},
+	}).Sort(), attrs)
+	assert.Equal(t, 1, md.ResourceMetrics().At(1).Resource().Attributes().Len(), "resources without matching metrics are not enriched")
+}
+
+func TestConfigValidateClient(t *testing.T) {
+	cfg := NewFactory().CreateDefaultConfig().(*Config)
+	require.NoError(t, cfg.Validate())
+
+	cfg.Client.Scheme = "ftp"
+	assert.Error(t, cfg.Validate())
+
+	cfg.Client.Scheme = "https"
+	cfg.Client.Endpoint = "https://example.com"
+	assert.Error(t, cfg.Validate())
+}
+
 func BenchmarkProcessTraces(b *testing.B) {
 	testCases := []testCase{
 		{
@@ -157,7 +552,7 @@ func BenchmarkProcessTraces(b *testing.B) {
 	require.NotNil(b, tp)
 
 	for _, tt := range testCases {
-		td := generateTraceData(tt.serviceName, tt.name, tt.inputAttributes)
+		td := generateTraceData(tt.serviceName, tt.name, tt.resourceAttributes, tt.inputAttributes)
 
 		b.Run(tt.name, func(b *testing.B) {
 			for i := 0; i < b.N; i++ {
@@ -167,7 +562,7 @@ func BenchmarkProcessTraces(b *testing.B) {
 
 		// Ensure that the modified `td` has the tiltAttributes sorted:
 		sortAttributes(td)
-		require.Equal(b, generateTraceData(tt.serviceName, tt.name, tt.expectedAttributes), td)
+		require.Equal(b, generateTraceData(tt.serviceName, tt.name, tt.resourceAttributes, tt.expectedAttributes), td)
 	}
 }
 
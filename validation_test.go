@@ -0,0 +1,65 @@
+package transparencyprocessor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opencensus.io/stats/view"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/config/configtelemetry"
+	"go.opentelemetry.io/collector/obsreport"
+)
+
+func TestUpdateAttributesValidation(t *testing.T) {
+	resetViews(t)
+
+	// legalBases is misspelled and most required sections are missing.
+	document := map[string]interface{}{
+		"dataDisclosed": []interface{}{map[string]interface{}{
+			"category":   "email",
+			"legalbasis": []interface{}{map[string]interface{}{"reference": "GDPR-6-1-a"}},
+		}},
+	}
+
+	tests := []struct {
+		mode      ValidationMode
+		wantErr   bool
+		wantAttrs bool
+	}{
+		{mode: ValidationReject, wantErr: true},
+		{mode: ValidationEnrich, wantAttrs: true},
+		{mode: ValidationLog},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.mode), func(t *testing.T) {
+			cfg := NewFactory().CreateDefaultConfig().(*Config)
+			cfg.Sources = []SourceConfig{{Type: sourceTypeStatic, Document: document}}
+			cfg.Validation = tt.mode
+			require.NoError(t, cfg.Validate())
+			set := componenttest.NewNopProcessorCreateSettings()
+			set.MetricsLevel = configtelemetry.LevelBasic
+			tp, err := newTransparencyProcessor(set, cfg)
+			require.NoError(t, err)
+
+			attr, err := tp.updateAttributes(context.Background(), "host", "/path")
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, []string{"email"}, attr.categories)
+			if tt.wantAttrs {
+				assert.NotEmpty(t, attr.validationErrors)
+			} else {
+				assert.Empty(t, attr.validationErrors)
+			}
+		})
+	}
+
+	rows, err := view.RetrieveData(obsreport.BuildProcessorCustomMetricName(typeStr, statInvalidDocuments.Name()))
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Equal(t, float64(len(tests)), rows[0].Data.(*view.SumData).Value)
+}